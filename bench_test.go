@@ -0,0 +1,461 @@
+package robots
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// syntheticRobots returns a robots.txt file with the given number of
+// groups, each with the given number of rules, in the style of the
+// files of large sites.
+func syntheticRobots(groups, rules int) string {
+	var b strings.Builder
+	b.WriteString("# Generated for benchmarks\n")
+	for g := 0; g < groups; g++ {
+		fmt.Fprintf(&b, "User-agent: bot-%c%c\n", 'a'+g%26, 'a'+g/26%26)
+		if g%2 == 0 {
+			fmt.Fprintf(&b, "User-agent: other-%c\n", 'a'+g%26)
+		}
+		fmt.Fprintf(&b, "Crawl-delay: %d\n", g%10)
+		for r := 0; r < rules; r++ {
+			switch r % 4 {
+			case 0:
+				fmt.Fprintf(&b, "Disallow: /section-%d/page-%d.html\n", g, r)
+			case 1:
+				fmt.Fprintf(&b, "Allow: /section-%d/*/public-%d$ # allowed\n", g, r)
+			case 2:
+				fmt.Fprintf(&b, "Disallow: /*?session=%d&*\n", r)
+			default:
+				fmt.Fprintf(&b, "disallow : /search/%d/\n", r)
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Sitemap: https://www.example.com/sitemap.xml\n")
+	return b.String()
+}
+
+// benchmarkInputs returns the files in testdata and synthetic files,
+// by name.
+func benchmarkInputs(b *testing.B) map[string]string {
+	inputs := map[string]string{
+		"synthetic-small":  syntheticRobots(5, 20),
+		"synthetic-large":  syntheticRobots(50, 200),
+		"synthetic-single": syntheticRobots(1, 10000),
+	}
+	fnames, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, fname := range fnames {
+		buf, err := ioutil.ReadFile(fname)
+		if err != nil {
+			b.Fatal(err)
+		}
+		inputs[filepath.Base(fname)] = string(buf)
+	}
+	return inputs
+}
+
+func BenchmarkLex(b *testing.B) {
+	for name, input := range benchmarkInputs(b) {
+		input := input
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l := lex(input)
+				for _, ok := l.nextItem(); ok; _, ok = l.nextItem() {
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkLexChannel lexes the same inputs as BenchmarkLex with the
+// goroutine lexer it replaced, for comparison.
+func BenchmarkLexChannel(b *testing.B) {
+	for name, input := range benchmarkInputs(b) {
+		input := input
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				chanLex(input)
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	for name, input := range benchmarkInputs(b) {
+		input := input
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				parse(input, config{})
+			}
+		})
+	}
+}
+
+var benchmarkPatterns = []string{
+	"/section-12/page-34.html",
+	"/section-*/*/public-5$",
+	"/*?session=6&*",
+	"/*.php$",
+}
+
+var benchmarkPaths = []string{
+	"/section-12/page-34.html",
+	"/section-3/archive/2019/public-5",
+	"/search/results?q=robots&session=6&page=2",
+	"/a/long/path/that/matches/none/of/the/patterns/index.html",
+}
+
+func BenchmarkMatch(b *testing.B) {
+	b.Run("pattern", func(b *testing.B) {
+		var patterns []pattern
+		for _, p := range benchmarkPatterns {
+			patterns = append(patterns, compilePattern(p))
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, p := range patterns {
+				for _, path := range benchmarkPaths {
+					p.match(path)
+				}
+			}
+		}
+	})
+	b.Run("regexp", func(b *testing.B) {
+		var patterns []*regexp.Regexp
+		for _, p := range benchmarkPatterns {
+			patterns = append(patterns, regexpPattern(p))
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, re := range patterns {
+				for _, path := range benchmarkPaths {
+					re.MatchString(path)
+				}
+			}
+		}
+	})
+}
+
+// literalRobots returns a robots.txt file with one group of the
+// given number of rules without wildcards, in many directories.
+func literalRobots(rules int) string {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for r := 0; r < rules; r++ {
+		fmt.Fprintf(&b, "Disallow: /dir-%d/page-%d.html\n", r%100, r)
+	}
+	return b.String()
+}
+
+// BenchmarkFind compares finding the rule that matches a path with
+// the index of a group and with a sequential scan of its members, for
+// groups of rules without wildcards and groups in which most rules
+// have them. Rules with wildcards are still tested one by one once
+// their literal prefix matches.
+func BenchmarkFind(b *testing.B) {
+	files := []struct {
+		name string
+		gen  func(rules int) string
+	}{
+		{"literal", literalRobots},
+		{"mixed", func(rules int) string { return syntheticRobots(1, rules) }},
+	}
+	for _, file := range files {
+		for _, rules := range []int{10, 100, 1000, 10000} {
+			r, _ := From(200, strings.NewReader(file.gen(rules)))
+			g := &r.agents[0].group
+			paths := append([]string{
+				fmt.Sprintf("/dir-%d/page-%d.html", rules/2%100, rules/2),
+				fmt.Sprintf("/section-0/page-%d.html", rules/2),
+				"/search/results?q=robots",
+			}, benchmarkPaths...)
+			b.Run(fmt.Sprintf("%s-%d/index", file.name, rules), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					for _, path := range paths {
+						g.find(path)
+					}
+				}
+			})
+			b.Run(fmt.Sprintf("%s-%d/scan", file.name, rules), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					for _, path := range paths {
+						g.scan(path)
+					}
+				}
+			})
+		}
+	}
+}
+
+// The rest of this file is the lexer that ran in a goroutine of its
+// own and sent each item over a channel, as it was before the lexer
+// became a pull-based state machine. It is kept only to be measured
+// against the current lexer in BenchmarkLexChannel.
+
+type chanLexer struct {
+	typ       membertype
+	input     string
+	start     int
+	pos       int
+	width     int
+	line      int // line number of pos, starting at 1
+	fieldPos  int // byte offset of the field being lexed
+	fieldLine int // line number of fieldPos
+	valuePos  int // byte offset of the value being lexed
+	items     chan *item
+}
+
+func (l *chanLexer) nextItem() *item {
+	return <-l.items
+}
+
+func (l *chanLexer) next() rune {
+	if l.pos >= len(l.input) {
+		l.width = 0
+		return eof
+	}
+	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+	l.width = w
+	l.pos += w
+	if r == '\n' {
+		l.line++
+	}
+	return r
+}
+
+func (l *chanLexer) backup() {
+	l.pos -= l.width
+	if l.width == 1 && l.input[l.pos] == '\n' {
+		l.line--
+	}
+}
+
+func (l *chanLexer) peek() rune {
+	r := l.next()
+	l.backup()
+	return r
+}
+
+func (l *chanLexer) emit() {
+	l.items <- &item{
+		typ:  l.typ,
+		val:  strings.TrimRightFunc(l.input[l.start:l.pos], unicode.IsSpace),
+		pos:  l.fieldPos,
+		vpos: l.valuePos,
+		line: l.fieldLine,
+	}
+	l.start = l.pos
+}
+
+func (l *chanLexer) ignore() {
+	l.start = l.pos
+}
+
+// Unlike model lexer, does not terminate lexing.  Per
+// https://developers.google.com/search/reference/robots_txt#abstract,
+// simply accept lines that are valid and silently discard those that
+// are not (even if received content is HTML).
+//
+// The error is positioned at the start of the unconsumed input,
+// unless that has moved past the line on which the field began. In
+// that case it is positioned at the field, since that is the line
+// being discarded.
+func (l *chanLexer) errorf(reason Reason, format string, args ...interface{}) {
+	pos, line := l.start, l.line
+	if line != l.fieldLine {
+		pos, line = l.fieldPos, l.fieldLine
+	}
+	l.items <- &item{
+		typ:    itemError,
+		val:    fmt.Sprintf(format, args...),
+		pos:    pos,
+		line:   line,
+		reason: reason,
+	}
+}
+
+func (l *chanLexer) run() {
+	for fn := chanLexStart; fn != nil; fn = fn(l) {
+	}
+	close(l.items)
+}
+
+func chanLex(in string) []*item {
+	l := &chanLexer{
+		input: in,
+		line:  1,
+		items: make(chan *item),
+	}
+	go l.run()
+	items := []*item{}
+	for item := l.nextItem(); item != nil; item = l.nextItem() {
+		items = append(items, item)
+	}
+	return items
+}
+
+type chanLexfn func(*chanLexer) chanLexfn
+
+func chanLexStart(l *chanLexer) chanLexfn {
+	c := l.peek()
+	switch {
+	case c == eof:
+		return nil
+	case c == '#':
+		return chanLexComment
+	case unicode.IsSpace(c):
+		chanSkipLWS(l)
+		return chanLexStart
+	default:
+		return chanLexField
+	}
+}
+
+func chanLexField(l *chanLexer) chanLexfn {
+	l.fieldPos = l.start
+	l.fieldLine = l.line
+	for field, typ := range membertypes {
+		if len(l.input[l.start:]) < len(field) {
+			// The remaining input is shorter than the
+			// field specifier.
+			continue
+		}
+		if strings.EqualFold(field, l.input[l.start:l.start+len(field)]) {
+			l.typ = typ
+			l.pos += len(field)
+			l.ignore()
+			return chanLexSep
+		}
+	}
+	// The input did not match a field. We emit an error and continue.
+	l.errorf(UnknownField, "unexpected field type: %s", l.input[l.start:])
+	return chanLexNextLine
+}
+
+// If we're here, the beginning of this line did not match a
+// specifier, and therefore the rest of the line cannot match
+// anything.
+func chanLexNextLine(l *chanLexer) chanLexfn {
+	for c := l.next(); c != '\n' && c != eof; c = l.next() {
+	}
+	l.ignore()
+	return chanLexStart
+}
+
+// Check for a separator, optionally with LWS on both sides. LWS that
+// ends the line without folding ends the record: the next line must
+// not be mistaken for the separator or value of this one.
+func chanLexSep(l *chanLexer) chanLexfn {
+	if !chanSkipLWS(l) {
+		l.errorf(MissingSeparator, "expected separator betweeen field and value")
+		return chanLexStart
+	}
+	if c := l.next(); c != ':' {
+		l.errorf(MissingSeparator, "expected separator betweeen field and value")
+		return chanLexNextLine
+	}
+	// An empty value is positioned immediately after the
+	// separator.
+	l.valuePos = l.pos
+	if !chanSkipLWS(l) {
+		// The value is empty.
+		l.emit()
+		return chanLexStart
+	}
+	l.valuePos = l.pos
+	return chanLexValue
+}
+
+func chanLexValue(l *chanLexer) chanLexfn {
+	// Per Google's specification, a value can consist of any character
+	// other than a control character or '#'.
+	for c := l.next(); !isCTL(c) && c != '#' && c != eof; c = l.next() {
+	}
+	l.backup()
+	l.emit()
+	return chanLexComment
+}
+
+func chanLexComment(l *chanLexer) chanLexfn {
+	more := chanSkipLWS(l)
+	if !more {
+		// New line of input
+		return chanLexStart
+	}
+	// We ran into something on the same logical line. What is it?
+	if c := l.peek(); c == '#' {
+		for c := l.next(); c != '\n' && c != eof; c = l.next() {
+		}
+		l.backup()
+		l.ignore()
+		return chanLexEOL
+	}
+	// The current line looked like it would continue, but it didn't.
+	// There was no comment. Therefore it actually ended on a newline.
+	// We should treat the next line as fresh input.
+	return chanLexStart
+}
+
+func chanLexEOL(l *chanLexer) chanLexfn {
+	c := l.next()
+	if c == '\n' {
+		l.ignore()
+		return chanLexStart
+	}
+	if c == eof {
+		l.ignore()
+		return chanLexStart
+	}
+	l.errorf(UnexpectedText, "expected EOL")
+	return chanLexNextLine
+}
+
+// LWS is defined in RFC 1945. "Linear whitespace" includes space and
+// tab characters, but optionally continues a logical line as long as
+// the continuation line starts with a space or tab.
+//
+// chanSkipLWS advances the chanLexer past any LWS, whether or not it
+// folds. The fold return value specifies whether the LWS spanned a
+// fold. If fold is true, the next input is a logical continuation of
+// the previous line. If fold is false, the next character represents
+// the start of a new line of input.
+//
+// See: http://www.ietf.org/rfc/rfc1945.txt
+func chanSkipLWS(l *chanLexer) (fold bool) {
+	afterEOL := false
+	fold = true
+	for c := l.next(); ; c = l.next() {
+		if c == '\n' {
+			afterEOL = true
+			continue
+		}
+		if afterEOL && !(c == ' ' || c == '\t') {
+			fold = false
+			break
+		}
+		if !unicode.IsSpace(c) {
+			break
+		}
+		afterEOL = false
+	}
+	l.backup()
+	l.ignore()
+	return fold
+}
@@ -0,0 +1,175 @@
+package robots
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A Builder constructs a robots.txt policy programmatically. Its
+// methods can be chained:
+//
+//	r, text, err := robots.NewBuilder().
+//		Group("Googlebot", "Bingbot").
+//		Disallow("/private").
+//		Allow("/private/ok").
+//		Sitemap("https://www.example.com/sitemap.xml").
+//		Build()
+//
+// Each method validates its arguments. After the first invalid
+// argument, the remaining calls have no effect, and Build reports
+// the error.
+type Builder struct {
+	groups   []*Group
+	sitemaps []string
+	err      error
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Group begins a new group of rules applying to the named agents.
+// Each name must be "*" or a product token: a non-empty string of
+// letters, hyphens and underscores.
+func (b *Builder) Group(agents ...string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(agents) == 0 {
+		b.err = fmt.Errorf("group has no agents")
+		return b
+	}
+	for _, agent := range agents {
+		if !isAgentName(agent) {
+			b.err = fmt.Errorf("invalid agent: %q", agent)
+			return b
+		}
+	}
+	b.groups = append(b.groups, &Group{
+		Agents: append([]string(nil), agents...),
+	})
+	return b
+}
+
+// Allow adds an allow rule to the current group.
+func (b *Builder) Allow(pattern string) *Builder {
+	return b.rule(true, pattern)
+}
+
+// Disallow adds a disallow rule to the current group.
+func (b *Builder) Disallow(pattern string) *Builder {
+	return b.rule(false, pattern)
+}
+
+func (b *Builder) rule(allow bool, pattern string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.groups) == 0 {
+		b.err = fmt.Errorf("rule before any group: %s", pattern)
+		return b
+	}
+	if err := checkPattern(pattern); err != nil {
+		b.err = err
+		return b
+	}
+	g := b.groups[len(b.groups)-1]
+	g.Rules = append(g.Rules, Rule{
+		Allow:   allow,
+		Pattern: pattern,
+	})
+	return b
+}
+
+// CrawlDelay sets the crawl delay of the current group.
+func (b *Builder) CrawlDelay(d time.Duration) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if len(b.groups) == 0 {
+		b.err = fmt.Errorf("crawl-delay before any group: %v", d)
+		return b
+	}
+	if d < 0 {
+		b.err = fmt.Errorf("negative crawl-delay: %v", d)
+		return b
+	}
+	g := b.groups[len(b.groups)-1]
+	g.CrawlDelay = d
+	g.HasCrawlDelay = true
+	return b
+}
+
+// Sitemap adds a sitemap. The URL must be absolute.
+func (b *Builder) Sitemap(rawurl string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if err := checkValue(rawurl); err != nil {
+		b.err = err
+		return b
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if !u.IsAbs() || u.Host == "" {
+		b.err = fmt.Errorf("expected absolute URL, got: %s", rawurl)
+		return b
+	}
+	b.sitemaps = append(b.sitemaps, rawurl)
+	return b
+}
+
+// Build returns the policy as a Robots object, as though it had been
+// served with a 200 status code, and as the text of a robots.txt file
+// in canonical form. If any call to b was invalid, Build returns the
+// first error.
+//
+// Options change how the Robots object interprets the policy, as they
+// do for From; see RFC9309, PrefixAgentMatching and FullUserAgents.
+// They do not change the text.
+func (b *Builder) Build(opts ...Option) (*Robots, string, error) {
+	if b.err != nil {
+		return nil, "", b.err
+	}
+	var buf bytes.Buffer
+	writeRobots(&buf, b.groups, b.sitemaps)
+	text := buf.String()
+	return makeRobots(200, parse(text, makeConfig(opts))), text, nil
+}
+
+// isAgentName reports whether name is "*" or a product token, as
+// defined in RFC 9309.
+func isAgentName(name string) bool {
+	if name == "*" {
+		return true
+	}
+	return name != "" && strings.IndexFunc(name, func(c rune) bool {
+		return !isTokenChar(c)
+	}) < 0
+}
+
+// isTokenChar reports whether c may appear in a product token.
+func isTokenChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_'
+}
+
+// checkPattern reports an error if pattern is not a path pattern that
+// can be written in a robots.txt file. A pattern must begin with '/'
+// or the wildcard '*', and may not contain whitespace.
+func checkPattern(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+		return fmt.Errorf("pattern must begin with / or *: %q", pattern)
+	}
+	if strings.IndexFunc(pattern, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("pattern contains whitespace: %q", pattern)
+	}
+	return checkValue(pattern)
+}
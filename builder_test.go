@@ -0,0 +1,94 @@
+package robots
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	r, text, err := NewBuilder().
+		Group("Googlebot", "Bingbot").
+		CrawlDelay(1500 * time.Millisecond).
+		Disallow("/private").
+		Allow("/private/ok").
+		Group("*").
+		Disallow("/*.pdf$").
+		Sitemap("https://www.example.com/sitemap.xml").
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	want := "User-agent: Googlebot\n" +
+		"User-agent: Bingbot\n" +
+		"Crawl-delay: 1.5\n" +
+		"Disallow: /private\n" +
+		"Allow: /private/ok\n" +
+		"\n" +
+		"User-agent: *\n" +
+		"Disallow: /*.pdf$\n" +
+		"\n" +
+		"Sitemap: https://www.example.com/sitemap.xml\n"
+	if text != want {
+		t.Errorf("Build text = %q, want %q", text, want)
+	}
+
+	var tests = []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"bingbot", "/private/page", false},
+		{"bingbot", "/private/ok", true},
+		{"Googlebot", "/doc.pdf", true},
+		{"otherbot", "/doc.pdf", false},
+		{"otherbot", "/private/page", true},
+	}
+	for _, test := range tests {
+		if got := r.Test(test.name, test.path); got != test.want {
+			t.Errorf("r.Test(%q, %q) = %t", test.name, test.path, got)
+		}
+	}
+	if d, ok := r.CrawlDelay("googlebot"); !ok || d != 1500*time.Millisecond {
+		t.Errorf("r.CrawlDelay(\"googlebot\") = %v, %t", d, ok)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		b    *Builder
+	}{
+		{"no agents", NewBuilder().Group()},
+		{"empty agent", NewBuilder().Group("")},
+		{"agent with space", NewBuilder().Group("Google bot")},
+		{"agent with version", NewBuilder().Group("Googlebot/2.1")},
+		{"rule before group", NewBuilder().Disallow("/")},
+		{"relative pattern", NewBuilder().Group("a").Disallow("private")},
+		{"empty pattern", NewBuilder().Group("a").Allow("")},
+		{"pattern with comment", NewBuilder().Group("a").Allow("/a#b")},
+		{"pattern with space", NewBuilder().Group("a").Allow("/a b")},
+		{"negative delay", NewBuilder().Group("a").CrawlDelay(-time.Second)},
+		{"relative sitemap", NewBuilder().Sitemap("/sitemap.xml")},
+		{"error is sticky", NewBuilder().Group("").Group("a").Disallow("/")},
+	}
+
+	for _, test := range tests {
+		if _, _, err := test.b.Build(); err == nil {
+			t.Errorf("%s: Build should fail", test.name)
+		}
+	}
+}
+
+func TestBuilderOptions(t *testing.T) {
+	b := NewBuilder().Group("googlebot").Disallow("/")
+
+	r, _, _ := b.Build()
+	if !r.Test("googlebot-news", "/") {
+		t.Errorf("Build without options did not use default agent matching")
+	}
+	r, _, _ = b.Build(PrefixAgentMatching())
+	if r.Test("googlebot-news", "/") {
+		t.Errorf("Build did not apply PrefixAgentMatching")
+	}
+}
@@ -0,0 +1,400 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a Cache keeps a robots.txt file if
+	// the response does not say otherwise. RFC 9309 asks crawlers
+	// not to use a cached copy for more than 24 hours.
+	DefaultTTL = 24 * time.Hour
+	// DefaultMaxStale is how long a Cache keeps serving the last
+	// good copy of a robots.txt file while the server is
+	// unreachable. This is the period suggested by RFC 9309.
+	DefaultMaxStale = 30 * 24 * time.Hour
+	// DefaultTimeout is how long a Cache waits for a robots.txt
+	// file before treating its server as unreachable.
+	DefaultTimeout = time.Minute
+)
+
+// A Cache fetches robots.txt files and keeps them for reuse. Files are
+// keyed by their URL, as returned by Locate, so one file serves every
+// URL in its scope. Concurrent requests for a file that is not cached
+// share a single fetch.
+//
+// A file is kept for TTL, or for the max-age of its Cache-Control
+// header. When it expires, it is revalidated with a conditional
+// request; if the server reports it unchanged, the compiled file is
+// kept for another period. If the server is unreachable, the last good
+// copy is served for up to MaxStale after it was fetched; after that,
+// the server's failure applies and all URLs are disallowed. The time a
+// file was fetched is recorded in the Fetched field of its Result.
+// When to try an unreachable server again, and when to give up and
+// allow all URLs, is decided by Retry.
+//
+// The zero value of a Cache is ready to use. A Cache is safe for
+// concurrent use by multiple goroutines.
+type Cache struct {
+	// Fetcher retrieves files. If nil, a zero Fetcher is used.
+	Fetcher *Fetcher
+
+	// TTL is how long files are kept if their response has no
+	// max-age. If zero, DefaultTTL is used.
+	TTL time.Duration
+
+	// MaxStale is how long the last good copy of a file is served
+	// while its server is unreachable. If zero, DefaultMaxStale is
+	// used.
+	MaxStale time.Duration
+
+	// Timeout limits each fetch. A fetch is shared by every caller
+	// waiting for the file, so it does not stop when the caller
+	// that started it gives up; it stops after Timeout, and the
+	// server is treated as unreachable. If zero, DefaultTimeout is
+	// used.
+	Timeout time.Duration
+
+	// Store, if not nil, keeps the files of the Cache in place of
+	// memory: to bound their number, to keep them beyond the life
+	// of the Cache, or to share them with other caches. Files are
+	// looked up in Store each time they are needed, so a file
+	// fetched by another cache sharing Store is used. An error
+	// from Store is treated as a missing file.
+	Store Store
+
+	// Retry decides when to fetch a file again after its server
+	// was unreachable, and when to assume there is no file. If
+	// nil, the zero RetryPolicy is used, with a Jitter of 0.1.
+	Retry *RetryPolicy
+
+	mu      sync.Mutex
+	entries map[string]*Result // if Store is nil
+	calls   map[string]*cacheCall
+	hosts   map[string]*hostState // of failing servers
+
+	now func() time.Time // for testing; time.Now if nil
+}
+
+// A hostState records the run of failed fetches of a file, if any.
+type hostState struct {
+	failures int
+	since    time.Time
+	code     int
+	err      error
+}
+
+// A cacheCall is a fetch in progress. done is closed when it
+// completes.
+type cacheCall struct {
+	done chan struct{}
+	res  *Result
+}
+
+// Fetch returns the robots.txt file that governs rawurl from the
+// cache, fetching it if it is missing or expired. As with
+// Fetcher.Fetch, failing to retrieve the file is not an error. Fetch
+// returns an error if rawurl is invalid, or if ctx is done before the
+// file is available. A fetch in progress continues for other callers
+// after ctx is done.
+func (c *Cache) Fetch(ctx context.Context, rawurl string) (*Result, error) {
+	key, err := Locate(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	if res := c.lookup(key); res != nil && c.clock().Before(res.Expires) {
+		return res, nil
+	}
+
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		call = &cacheCall{done: make(chan struct{})}
+		if c.calls == nil {
+			c.calls = make(map[string]*cacheCall)
+		}
+		c.calls[key] = call
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		go func() {
+			// The fetch keeps the values of ctx, but not its
+			// cancellation, which belongs to this caller alone.
+			fctx, cancel := context.WithTimeout(detachedContext{ctx}, c.timeout())
+			defer cancel()
+			call.res = c.fetch(fctx, key)
+			c.mu.Lock()
+			delete(c.calls, key)
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+
+	select {
+	case <-call.done:
+		return call.res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// A detachedContext has the values of its parent, but is never done.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// fetch retrieves the file at key and updates its cache entry, unless
+// the entry was updated since it was found expired. A good copy of the
+// file is revalidated rather than fetched again.
+func (c *Cache) fetch(ctx context.Context, key string) *Result {
+	prev := c.lookup(key)
+	if prev != nil && c.clock().Before(prev.Expires) {
+		return prev
+	}
+
+	f := c.Fetcher
+	if f == nil {
+		f = &Fetcher{}
+	}
+	var res *Result
+	var err error
+	if isGood(prev) {
+		res, err = f.Revalidate(ctx, prev)
+	} else {
+		res, err = f.Fetch(ctx, key)
+	}
+	if err != nil {
+		// Not reached: key is the output of Locate.
+		res = &Result{URL: key, FinalURL: key}
+		res.fail(err, f.Options)
+	}
+
+	now := c.clock()
+	res.Fetched = now
+	h := c.record(key, res, now)
+	if isGood(res) {
+		res.Expires = now.Add(c.ttl(res.Header))
+		c.save(key, res)
+		return res
+	}
+	retry := c.Retry
+	if retry == nil {
+		retry = &defaultRetryPolicy
+	}
+	wait := retry.wait(h.failures, res.Header, now)
+	switch {
+	case isGood(prev) && now.Before(prev.Fetched.Add(c.maxStale())):
+		stale := *prev
+		stale.Expires = now.Add(wait)
+		res = &stale
+	case retry.assumeAllowed(now.Sub(h.since)):
+		r := *res.Robots
+		r.allow = true
+		res.Robots = &r
+		fallthrough
+	default:
+		res.Expires = now.Add(wait)
+	}
+	c.save(key, res)
+	return res
+}
+
+// record updates the run of failed fetches of the file at key with
+// res, and returns a copy of it.
+func (c *Cache) record(key string, res *Result, now time.Time) hostState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if isGood(res) {
+		delete(c.hosts, key)
+		return hostState{}
+	}
+	if c.hosts == nil {
+		c.hosts = make(map[string]*hostState)
+	}
+	h, ok := c.hosts[key]
+	if !ok {
+		h = &hostState{since: now}
+		c.hosts[key] = h
+	}
+	h.failures++
+	h.code = res.StatusCode
+	h.err = res.Err
+	return *h
+}
+
+// State reports what c knows about the robots.txt file that governs
+// rawurl, and whether c has it in memory.
+func (c *Cache) State(rawurl string) (HostState, bool) {
+	key, err := Locate(rawurl)
+	if err != nil {
+		return HostState{}, false
+	}
+	return c.state(key)
+}
+
+// States reports what c knows about each robots.txt file it has in
+// memory, in order of URL. If c has a Store, which cannot be listed,
+// only the files whose servers are failing are reported.
+func (c *Cache) States() []HostState {
+	c.mu.Lock()
+	var keys []string
+	if c.Store != nil {
+		for key := range c.hosts {
+			keys = append(keys, key)
+		}
+	} else {
+		for key := range c.entries {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.Unlock()
+	sort.Strings(keys)
+	states := make([]HostState, 0, len(keys))
+	for _, key := range keys {
+		if s, ok := c.state(key); ok {
+			states = append(states, s)
+		}
+	}
+	return states
+}
+
+// state returns the state of the file at key.
+func (c *Cache) state(key string) (HostState, bool) {
+	res := c.lookup(key)
+	if res == nil {
+		return HostState{}, false
+	}
+	s := HostState{
+		URL:     key,
+		Status:  res.Status(),
+		Fetched: res.Fetched,
+		Expires: res.Expires,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h := c.hosts[key]; h != nil {
+		s.Failures = h.failures
+		s.FailingSince = h.since
+		s.StatusCode = h.code
+		s.Err = h.err
+		s.Stale = isGood(res)
+		s.Assumed = !isGood(res) && res.allow
+	}
+	return s, true
+}
+
+// lookup returns the entry for key, or nil if there is none. An entry
+// may have expired.
+func (c *Cache) lookup(key string) *Result {
+	if c.Store != nil {
+		res, err := c.Store.Get(key)
+		if err != nil {
+			return nil
+		}
+		return res
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key]
+}
+
+// save makes res the entry for key. A good copy is stored for long
+// enough to be served stale.
+func (c *Cache) save(key string, res *Result) {
+	if c.Store == nil {
+		c.mu.Lock()
+		if c.entries == nil {
+			c.entries = make(map[string]*Result)
+		}
+		c.entries[key] = res
+		c.mu.Unlock()
+		return
+	}
+	expires := res.Expires
+	if kept := res.Fetched.Add(c.maxStale()); isGood(res) && kept.After(expires) {
+		expires = kept
+	}
+	c.Store.Put(key, res, expires)
+}
+
+// isGood reports whether res is a good copy of a robots.txt file: one
+// that was retrieved, even if it was found missing, rather than one
+// recording that the server was unreachable.
+func isGood(res *Result) bool {
+	return res != nil && res.Status() != Unreachable
+}
+
+// Allowed reports whether agent may crawl rawurl, according to the
+// robots.txt file that governs it. It returns an error if rawurl is
+// invalid, or if ctx is done while waiting for the file.
+func (c *Cache) Allowed(ctx context.Context, agent, rawurl string) (bool, error) {
+	res, err := c.Fetch(ctx, rawurl)
+	if err != nil {
+		return false, err
+	}
+	return res.Test(agent, rawurl), nil
+}
+
+func (c *Cache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// ttl returns how long to keep a file served with header h.
+func (c *Cache) ttl(h http.Header) time.Duration {
+	if d, ok := maxAge(h); ok {
+		return d
+	}
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultTTL
+}
+
+func (c *Cache) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (c *Cache) maxStale() time.Duration {
+	if c.MaxStale > 0 {
+		return c.MaxStale
+	}
+	return DefaultMaxStale
+}
+
+// maxAge returns the max-age directive of the Cache-Control header in
+// h, and whether there is a valid one.
+func maxAge(h http.Header) (time.Duration, bool) {
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if len(directive) < len("max-age=") || !strings.EqualFold(directive[:len("max-age=")], "max-age=") {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.Trim(directive[len("max-age="):], `"`), 10, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		if n > int64(1<<63-1)/int64(time.Second) {
+			n = int64(1<<63-1) / int64(time.Second)
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	return 0, false
+}
@@ -0,0 +1,416 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// robotsServer serves a robots.txt file disallowing /private, or the
+// status code in status if it is not zero, and counts requests. If
+// header has an ETag, conditional requests matching it get a 304
+// response.
+type robotsServer struct {
+	*httptest.Server
+	requests    int32
+	notModified int32
+	status      int32
+	header      http.Header
+}
+
+func newRobotsServer() *robotsServer {
+	s := &robotsServer{header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		for k, v := range s.header {
+			w.Header()[k] = v
+		}
+		if etag := s.header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&s.notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if status := atomic.LoadInt32(&s.status); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+		fmt.Fprint(w, "user-agent: *\ndisallow: /private\n")
+	}))
+	return s
+}
+
+// fakeClock is a Clock that only moves when told to. Waiting moves it
+// forward immediately, and the waits are recorded.
+type fakeClock struct {
+	mu    sync.Mutex
+	t     time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	c.t = c.t.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.t
+	return ch
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestCache() (*Cache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	return &Cache{now: clock.Now}, clock
+}
+
+func TestCacheAllowed(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	c, _ := newTestCache()
+
+	var tests = []struct {
+		url  string
+		want bool
+	}{
+		{s.URL + "/private", false},
+		{s.URL + "/public", true},
+		{s.URL + "/private/page.html", false},
+		{s.URL + "/", true},
+	}
+
+	for _, test := range tests {
+		got, err := c.Allowed(context.Background(), "Crawlerbot", test.url)
+		if err != nil {
+			t.Fatalf("Allowed returned error: %v", err)
+		}
+		if got != test.want {
+			t.Errorf("Allowed(%q) = %t, want %t", test.url, got, test.want)
+		}
+	}
+	if s.requests != 1 {
+		t.Errorf("got %d requests for one scope, want 1", s.requests)
+	}
+	if _, err := c.Allowed(context.Background(), "Crawlerbot", "/relative"); err == nil {
+		t.Errorf("Allowed of relative URL should fail")
+	}
+}
+
+func TestCacheSingleFetch(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		started <- struct{}{}
+		<-release
+		fmt.Fprint(w, "user-agent: *\ndisallow: /\n")
+	}))
+	defer ts.Close()
+	c, _ := newTestCache()
+
+	const n = 10
+	results := make(chan *Result, n)
+	fetch := func() {
+		res, err := c.Fetch(context.Background(), ts.URL)
+		if err != nil {
+			t.Errorf("Fetch returned error: %v", err)
+		}
+		results <- res
+	}
+	go fetch()
+	<-started
+	for i := 1; i < n; i++ {
+		go fetch()
+	}
+	close(release)
+
+	first := <-results
+	for i := 1; i < n; i++ {
+		if res := <-results; res != first {
+			t.Errorf("concurrent fetches returned different results")
+		}
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+// TestCacheLeaderCancel checks that the caller that starts a fetch can
+// give up without failing the fetch for others waiting on it.
+func TestCacheLeaderCancel(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		started <- struct{}{}
+		<-release
+		fmt.Fprint(w, "user-agent: *\ndisallow: /private\n")
+	}))
+	defer ts.Close()
+	c, _ := newTestCache()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := c.Fetch(ctx, ts.URL)
+		leader <- err
+	}()
+	<-started
+	type result struct {
+		ok  bool
+		err error
+	}
+	waiter := make(chan result, 1)
+	go func() {
+		ok, err := c.Allowed(context.Background(), "Crawlerbot", ts.URL+"/public")
+		waiter <- result{ok, err}
+	}()
+
+	cancel()
+	if err := <-leader; err != context.Canceled {
+		t.Errorf("cancelled Fetch returned %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if r := <-waiter; !r.ok || r.err != nil {
+		t.Errorf("waiting Allowed = %t, %v, want true, nil", r.ok, r.err)
+	}
+	if res, _ := c.Fetch(context.Background(), ts.URL); res.Status() != Parsed {
+		t.Errorf("cached status = %v, want %v", res.Status(), Parsed)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestCacheTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	c, _ := newTestCache()
+	c.Timeout = 10 * time.Millisecond
+
+	res, err := c.Fetch(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if res.Status() != Unreachable {
+		t.Errorf("status after timeout = %v, want %v", res.Status(), Unreachable)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	c, clock := newTestCache()
+	ctx := context.Background()
+
+	var steps = []struct {
+		advance  time.Duration
+		requests int32
+	}{
+		{0, 1},
+		{23 * time.Hour, 1},
+		{2 * time.Hour, 2},
+		{time.Hour, 2},
+	}
+	for i, step := range steps {
+		clock.advance(step.advance)
+		c.Fetch(ctx, s.URL)
+		if s.requests != step.requests {
+			t.Errorf("step %d: got %d requests, want %d", i, s.requests, step.requests)
+		}
+	}
+
+	s.header.Set("Cache-Control", "public, max-age=60")
+	clock.advance(DefaultTTL)
+	res, _ := c.Fetch(ctx, s.URL)
+	if want := clock.Now().Add(time.Minute); !res.Expires.Equal(want) {
+		t.Errorf("res.Expires = %v, want %v", res.Expires, want)
+	}
+	clock.advance(2 * time.Minute)
+	c.Fetch(ctx, s.URL)
+	if s.requests != 4 {
+		t.Errorf("max-age not honored: got %d requests, want 4", s.requests)
+	}
+}
+
+func TestCacheStale(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	c, clock := newTestCache()
+	c.MaxStale = 7 * 24 * time.Hour
+	c.Retry = &RetryPolicy{Max: time.Minute, AssumeAllowed: -1}
+	ctx := context.Background()
+
+	c.Fetch(ctx, s.URL)
+	atomic.StoreInt32(&s.status, http.StatusServiceUnavailable)
+
+	// The last good copy is served during an outage...
+	clock.advance(2 * DefaultTTL)
+	res, _ := c.Fetch(ctx, s.URL)
+	if res.Status() != Parsed || !res.Test("Crawlerbot", "/public") {
+		t.Errorf("stale copy not served: status %v", res.Status())
+	}
+	// ...and the server is tried again soon after.
+	requests := s.requests
+	clock.advance(time.Minute + time.Second)
+	c.Fetch(ctx, s.URL)
+	if s.requests != requests+1 {
+		t.Errorf("got %d requests, want %d", s.requests, requests+1)
+	}
+
+	// After MaxStale, the outage applies.
+	clock.advance(c.MaxStale)
+	res, _ = c.Fetch(ctx, s.URL)
+	if res.Status() != Unreachable || res.Test("Crawlerbot", "/public") {
+		t.Errorf("stale copy served after MaxStale: status %v", res.Status())
+	}
+
+	// When the server recovers, its file is used again.
+	atomic.StoreInt32(&s.status, 0)
+	clock.advance(time.Minute + time.Second)
+	res, _ = c.Fetch(ctx, s.URL)
+	if res.Status() != Parsed || !res.Test("Crawlerbot", "/public") {
+		t.Errorf("recovered file not used: status %v", res.Status())
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	var tests = []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"max-age=3600", time.Hour, true},
+		{"public, Max-Age=60", time.Minute, true},
+		{`max-age="60"`, time.Minute, true},
+		{"max-age=0", 0, true},
+		{"max-age=-1", 0, false},
+		{"max-age=soon", 0, false},
+		{"s-maxage=60", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := maxAge(http.Header{"Cache-Control": {test.header}})
+		if got != test.want || ok != test.ok {
+			t.Errorf("maxAge(%q) = %v, %t, want %v, %t", test.header, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	s.header.Set("ETag", `"v1"`)
+	c, clock := newTestCache()
+	ctx := context.Background()
+
+	first, _ := c.Fetch(ctx, s.URL)
+	clock.advance(DefaultTTL + time.Second)
+	second, _ := c.Fetch(ctx, s.URL)
+	if s.notModified != 1 {
+		t.Fatalf("got %d 304 responses, want 1", s.notModified)
+	}
+	if second.Robots != first.Robots {
+		t.Errorf("304 response replaced the compiled file")
+	}
+	if want := clock.Now().Add(DefaultTTL); !second.Expires.Equal(want) {
+		t.Errorf("second.Expires = %v, want %v", second.Expires, want)
+	}
+
+	clock.advance(time.Hour)
+	c.Fetch(ctx, s.URL)
+	if s.requests != 2 {
+		t.Errorf("got %d requests, want 2", s.requests)
+	}
+}
+
+// TestCacheMemoryStore checks that a Cache keeps its files in its
+// Store, so that a bounded Store bounds the Cache.
+func TestCacheMemoryStore(t *testing.T) {
+	store := NewMemoryStore(2)
+	c := &Cache{Store: store}
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		s := newRobotsServer()
+		if ok, _ := c.Allowed(ctx, "Crawlerbot", s.URL+"/private"); ok {
+			t.Errorf("/private allowed")
+		}
+		s.Close()
+		if n := store.Len(); n > 2 {
+			t.Errorf("store holds %d files, want at most 2", n)
+		}
+	}
+	if len(c.entries) != 0 {
+		t.Errorf("cache holds %d files outside its store", len(c.entries))
+	}
+}
+
+// TestCacheSharedStore checks that caches sharing a Store see each
+// other's fetches.
+func TestCacheSharedStore(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore(0)
+	store.Clock = clock
+	a := &Cache{Store: store, now: clock.Now}
+	b := &Cache{Store: store, now: clock.Now}
+	ctx := context.Background()
+
+	a.Fetch(ctx, s.URL)
+	b.Fetch(ctx, s.URL)
+	clock.advance(DefaultTTL + time.Second)
+	atomic.StoreInt32(&s.status, http.StatusNotFound)
+	a.Fetch(ctx, s.URL)
+	if ok, _ := b.Allowed(ctx, "Crawlerbot", s.URL+"/private"); !ok {
+		t.Errorf("/private disallowed after another cache found no file")
+	}
+	if s.requests != 2 {
+		t.Errorf("got %d requests, want 2", s.requests)
+	}
+}
+
+// TestCacheStoreClock checks that a Store sharing the clock of its
+// Cache keeps a file for as long as the Cache serves it stale.
+func TestCacheStoreClock(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	c, clock := newTestCache()
+	store := NewMemoryStore(0)
+	store.Clock = clock
+	c.Store = store
+	ctx := context.Background()
+
+	c.Fetch(ctx, s.URL)
+	atomic.StoreInt32(&s.status, http.StatusServiceUnavailable)
+	clock.advance(DefaultTTL + time.Second)
+	if res, _ := c.Fetch(ctx, s.URL); res.Status() != Parsed {
+		t.Errorf("status while unreachable = %v, want stale %v", res.Status(), Parsed)
+	}
+	clock.advance(DefaultMaxStale)
+	if res, _ := store.Get(s.URL + "/robots.txt"); res != nil && isGood(res) {
+		t.Errorf("store kept a good copy past MaxStale")
+	}
+}
@@ -0,0 +1,373 @@
+package robots
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A File is a robots.txt file represented losslessly: every byte of
+// the input is kept, including comments, blank lines, invalid lines
+// and the original spelling of fields. A File can be edited, and an
+// unedited File is written out exactly as it was read.
+//
+// A File is meant for tools that change robots.txt files on behalf of
+// their authors. To test URLs against a file, use From, or parse the
+// output of a File with From.
+type File struct {
+	lines []*line
+	eol   string // Line ending used for inserted lines.
+}
+
+// A line is a line of a File, including its line ending. A line
+// holds the directives that begin on it. Usually there is at most
+// one, but a record may span several physical lines when it folds,
+// and a control character may end one record and begin another on
+// the same line.
+type line struct {
+	text       string
+	directives []*Directive
+}
+
+// A Directive is a valid field and value within a File.
+type Directive struct {
+	typ  membertype
+	line *line
+	// Offsets within line.text of the start of the field, and of
+	// the start and end of the value.
+	start, vstart, vend int
+}
+
+// Field returns the lowercase name of the field of d, like
+// "user-agent" or "disallow".
+func (d *Directive) Field() string {
+	for field, typ := range membertypes {
+		if typ == d.typ {
+			return field
+		}
+	}
+	return ""
+}
+
+// Value returns the value of d.
+func (d *Directive) Value() string {
+	return d.line.text[d.vstart:d.vend]
+}
+
+// ParseFile reads a robots.txt file from in and returns its lossless
+// representation. Like From, it accepts any input; invalid lines are
+// kept as they are, but contain no directives. ParseFile will only
+// signal an error condition if it fails to read from the input at
+// all.
+func ParseFile(in io.Reader) (*File, error) {
+	buf, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	src := string(buf)
+	f := &File{eol: "\n"}
+	if i := strings.IndexByte(src, '\n'); i > 0 && src[i-1] == '\r' {
+		f.eol = "\r\n"
+	}
+
+	// The lexer does not see the byte order mark, so offsets of
+	// items are relative to the text that follows it.
+	bom := len(src) - len(stripBOM(src))
+	pos := 0        // End of the text accounted for so far.
+	lineStart := -1 // Start of the last line holding directives.
+	l := lex(src[bom:])
+	for it, ok := l.nextItem(); ok; it, ok = l.nextItem() {
+		if it.typ == itemError {
+			continue
+		}
+		start := it.pos + bom
+		vstart := it.vpos + bom
+		vend := vstart + len(it.val)
+		end := strings.IndexByte(src[vend:], '\n')
+		if end < 0 {
+			end = len(src)
+		} else {
+			end += vend + 1
+		}
+
+		var l *line
+		if start < pos {
+			// This directive begins on the same line as the
+			// last one, so that line is extended.
+			l = f.lines[len(f.lines)-1]
+			l.text = src[lineStart:end]
+		} else {
+			lineStart = strings.LastIndexByte(src[:start], '\n') + 1
+			f.appendText(src[pos:lineStart])
+			l = &line{text: src[lineStart:end]}
+			f.lines = append(f.lines, l)
+		}
+		l.directives = append(l.directives, &Directive{
+			typ:    it.typ,
+			line:   l,
+			start:  start - lineStart,
+			vstart: vstart - lineStart,
+			vend:   vend - lineStart,
+		})
+		pos = end
+	}
+	f.appendText(src[pos:])
+	return f, nil
+}
+
+// appendText adds s to f as lines without directives.
+func (f *File) appendText(s string) {
+	for s != "" {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		f.lines = append(f.lines, &line{text: s[:i]})
+		s = s[i:]
+	}
+}
+
+// WriteTo writes f to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, strings.NewReader(f.String()))
+}
+
+// String returns the text of f.
+func (f *File) String() string {
+	var b bytes.Buffer
+	for _, l := range f.lines {
+		b.WriteString(l.text)
+	}
+	return b.String()
+}
+
+// Directives returns the directives of f in the order they occur.
+func (f *File) Directives() []*Directive {
+	var directives []*Directive
+	for _, l := range f.lines {
+		directives = append(directives, l.directives...)
+	}
+	return directives
+}
+
+// SetValue changes the value of d to value. Everything else on the
+// line of d, including the spelling of its field and any comment, is
+// kept.
+func (f *File) SetValue(d *Directive, value string) error {
+	if err := checkValue(value); err != nil {
+		return err
+	}
+	l := d.line
+	text := l.text[:d.vstart]
+	if value != "" && strings.HasSuffix(text, ":") {
+		text += " "
+	}
+	text += value
+	delta := len(text) - d.vend
+	l.text = text + l.text[d.vend:]
+	d.vend = len(text)
+	d.vstart = d.vend - len(value)
+	for _, other := range l.directives {
+		if other.start > d.start {
+			other.start += delta
+			other.vstart += delta
+			other.vend += delta
+		}
+	}
+	return nil
+}
+
+// Remove removes d from f. If d is the only directive on its line,
+// the whole line is removed, including any comment on it.
+//
+// Removing a directive can change how the rest of the file is
+// grouped: removing the last rule of a group joins its agents to the
+// next group.
+func (f *File) Remove(d *Directive) error {
+	i := f.index(d.line)
+	if i < 0 {
+		return fmt.Errorf("directive is not in file: %s: %s", d.Field(), d.Value())
+	}
+	l := d.line
+	if len(l.directives) == 1 {
+		f.lines = append(f.lines[:i], f.lines[i+1:]...)
+		if i > 0 && i == len(f.lines) {
+			// The removed line was the last one. If it had no
+			// line ending, neither should the new last line.
+			if !strings.HasSuffix(l.text, "\n") {
+				prev := f.lines[i-1]
+				prev.text = strings.TrimSuffix(strings.TrimSuffix(prev.text, "\n"), "\r")
+			}
+		}
+		return nil
+	}
+	// Other directives share the line. Cut out the text from the
+	// start of d to the start of the next directive, or the end
+	// of the value of d if it is the last.
+	end := d.vend
+	for _, other := range l.directives {
+		if other.start > d.start && (end == d.vend || other.start < end) {
+			end = other.start
+		}
+	}
+	l.text = l.text[:d.start] + l.text[end:]
+	delta := end - d.start
+	var directives []*Directive
+	for _, other := range l.directives {
+		if other == d {
+			continue
+		}
+		if other.start > d.start {
+			other.start -= delta
+			other.vstart -= delta
+			other.vend -= delta
+		}
+		directives = append(directives, other)
+	}
+	l.directives = directives
+	return nil
+}
+
+// InsertAfter inserts a new line holding a directive with the given
+// field and value after the line holding d, and returns the new
+// directive. The field is a name like "disallow"; case is not
+// significant.
+func (f *File) InsertAfter(d *Directive, field, value string) (*Directive, error) {
+	i := f.index(d.line)
+	if i < 0 {
+		return nil, fmt.Errorf("directive is not in file: %s: %s", d.Field(), d.Value())
+	}
+	return f.insert(i+1, field, value)
+}
+
+// Append adds a new line holding a directive with the given field and
+// value to the end of f, and returns the new directive. The field is
+// a name like "sitemap"; case is not significant.
+func (f *File) Append(field, value string) (*Directive, error) {
+	return f.insert(len(f.lines), field, value)
+}
+
+// AddRule adds an allow or disallow rule with the given pattern to the
+// first group of f that names agent, after the last rule of that
+// group. Agent names are compared without regard to case.
+func (f *File) AddRule(agent string, allow bool, pattern string) (*Directive, error) {
+	field := "disallow"
+	if allow {
+		field = "allow"
+	}
+	for _, g := range f.groups() {
+		for _, d := range g {
+			if d.typ == itemUserAgent && strings.EqualFold(d.Value(), agent) {
+				return f.InsertAfter(g[len(g)-1], field, pattern)
+			}
+		}
+	}
+	return nil, fmt.Errorf("no group for agent: %s", agent)
+}
+
+// AddSitemap adds a sitemap directive with the given URL to the end of
+// f.
+func (f *File) AddSitemap(url string) (*Directive, error) {
+	return f.Append("sitemap", url)
+}
+
+// RemoveSitemap removes every sitemap directive with the given URL
+// from f. It reports whether any were removed.
+func (f *File) RemoveSitemap(url string) bool {
+	removed := false
+	for _, d := range f.Directives() {
+		if d.typ == itemSitemap && d.Value() == url {
+			f.Remove(d)
+			removed = true
+		}
+	}
+	return removed
+}
+
+func (f *File) insert(i int, field, value string) (*Directive, error) {
+	typ, ok := membertypes[strings.ToLower(field)]
+	if !ok {
+		return nil, fmt.Errorf("unknown field: %s", field)
+	}
+	if err := checkValue(value); err != nil {
+		return nil, err
+	}
+	if i > 0 {
+		// The new line must not run on from the previous one.
+		prev := f.lines[i-1]
+		if !strings.HasSuffix(prev.text, "\n") {
+			prev.text += f.eol
+		}
+	}
+	l := &line{}
+	d := &Directive{typ: typ, line: l}
+	l.text = fieldNames[typ] + ":"
+	if value != "" {
+		l.text += " "
+	}
+	d.vstart = len(l.text)
+	l.text += value
+	d.vend = len(l.text)
+	l.text += f.eol
+	l.directives = []*Directive{d}
+	f.lines = append(f.lines, nil)
+	copy(f.lines[i+1:], f.lines[i:])
+	f.lines[i] = l
+	return d, nil
+}
+
+// index returns the index of l in f, or -1 if l is not in f.
+func (f *File) index(l *line) int {
+	for i, other := range f.lines {
+		if other == l {
+			return i
+		}
+	}
+	return -1
+}
+
+// groups returns the directives of f arranged into groups, as the
+// parser would group them: each group is a list of user-agent
+// directives followed by the member directives that apply to
+// them. Sitemaps and members before the first user-agent are not part
+// of any group.
+func (f *File) groups() [][]*Directive {
+	var groups [][]*Directive
+	withinGroup := false
+	for _, d := range f.Directives() {
+		switch d.typ {
+		case itemSitemap:
+			continue
+		case itemUserAgent:
+			if withinGroup || len(groups) == 0 {
+				groups = append(groups, nil)
+			}
+			withinGroup = false
+		default:
+			withinGroup = true
+			if len(groups) == 0 {
+				continue
+			}
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], d)
+	}
+	return groups
+}
+
+// checkValue reports an error if value cannot be written as the value
+// of a directive: it must not contain control characters or '#', and
+// must not begin or end with whitespace, since that would be lost
+// when the file is read.
+func checkValue(value string) error {
+	for _, c := range value {
+		if isCTL(c) || c == '#' {
+			return fmt.Errorf("invalid character %q in value: %s", c, value)
+		}
+	}
+	if strings.TrimSpace(value) != value {
+		return fmt.Errorf("value has surrounding whitespace: %q", value)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package robots
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseFileString(t *testing.T, s string) *File {
+	f, err := ParseFile(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	return f
+}
+
+func TestFileLossless(t *testing.T) {
+	fnames, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []string{
+		"",
+		"\n\n",
+		"user-agent: a",
+		"user-agent: a\r\ndisallow: /\r\n",
+		"\ufeffuser-agent: a\n",
+		"user-agent\n  : a # folded\n",
+		"disallow: /a\tallow: /b\t# two on a line\n",
+		"# only a comment",
+		"<html>\n<body>disallow: /</body>",
+	}
+	for _, fname := range fnames {
+		buf, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(buf))
+	}
+
+	for _, input := range inputs {
+		if got := parseFileString(t, input).String(); got != input {
+			t.Errorf("ParseFile(%q).String() = %q", input, got)
+		}
+	}
+}
+
+func TestFileDirectives(t *testing.T) {
+	f := parseFileString(t, "\ufeffUSER-AGENT : a # c\n<p>\n  Disallow:/x\tallow: /y\nsitemap:\n")
+	var tests = []struct {
+		field string
+		value string
+	}{
+		{"user-agent", "a"},
+		{"disallow", "/x"},
+		{"allow", "/y"},
+		{"sitemap", ""},
+	}
+
+	got := f.Directives()
+	if len(got) != len(tests) {
+		t.Fatalf("got %d directives, want %d", len(got), len(tests))
+	}
+	for i, test := range tests {
+		if got[i].Field() != test.field || got[i].Value() != test.value {
+			t.Errorf("directive %d = %s: %q, want %s: %q",
+				i, got[i].Field(), got[i].Value(), test.field, test.value)
+		}
+	}
+}
+
+func TestFileEdit(t *testing.T) {
+	input := strings.Join([]string{
+		"# Our robots file",
+		"User-Agent: Googlebot  # the big one",
+		"DISALLOW: /private",
+		"",
+		"user-agent: *",
+		"disallow:",
+		"",
+		"Sitemap: http://example.com/old.xml",
+		"Sitemap: http://example.com/keep.xml # keep me",
+	}, "\r\n")
+	want := strings.Join([]string{
+		"# Our robots file",
+		"User-Agent: Googlebot  # the big one",
+		"DISALLOW: /secret",
+		"Allow: /secret/ok",
+		"",
+		"user-agent: *",
+		"disallow: /tmp",
+		"",
+		"Sitemap: http://example.com/keep.xml # keep me",
+		"Sitemap: http://example.com/new.xml",
+		"",
+	}, "\r\n")
+
+	f := parseFileString(t, input)
+	if _, err := f.AddRule("googlebot", true, "/secret/ok"); err != nil {
+		t.Errorf("AddRule returned error: %v", err)
+	}
+	for _, d := range f.Directives() {
+		switch {
+		case d.Value() == "/private":
+			f.SetValue(d, "/secret")
+		case d.Field() == "disallow" && d.Value() == "":
+			f.SetValue(d, "/tmp")
+		}
+	}
+	if !f.RemoveSitemap("http://example.com/old.xml") {
+		t.Errorf("RemoveSitemap reported nothing removed")
+	}
+	if _, err := f.AddSitemap("http://example.com/new.xml"); err != nil {
+		t.Errorf("AddSitemap returned error: %v", err)
+	}
+
+	if got := f.String(); got != want {
+		t.Errorf("edited file = %q, want %q", got, want)
+	}
+}
+
+func TestFileRemoveSharedLine(t *testing.T) {
+	f := parseFileString(t, "user-agent: a\ndisallow: /a\tallow: /b\tdisallow: /c\n")
+	d := f.Directives()
+	f.Remove(d[2])
+	if got, want := f.String(), "user-agent: a\ndisallow: /a\tdisallow: /c\n"; got != want {
+		t.Errorf("after Remove, f = %q, want %q", got, want)
+	}
+	f.SetValue(d[1], "/aa")
+	f.Remove(d[3])
+	if got, want := f.String(), "user-agent: a\ndisallow: /aa\t\n"; got != want {
+		t.Errorf("after Remove, f = %q, want %q", got, want)
+	}
+}
+
+func TestFileRemoveLast(t *testing.T) {
+	f := parseFileString(t, "user-agent: a\ndisallow: /")
+	d := f.Directives()
+	f.Remove(d[1])
+	if got, want := f.String(), "user-agent: a"; got != want {
+		t.Errorf("after Remove, f = %q, want %q", got, want)
+	}
+	if err := f.Remove(d[1]); err == nil {
+		t.Errorf("removing a directive twice should fail")
+	}
+}
+
+func TestFileInvalidEdits(t *testing.T) {
+	f := parseFileString(t, "user-agent: a\n")
+	d := f.Directives()[0]
+	if _, err := f.InsertAfter(d, "noindex", "/"); err == nil {
+		t.Errorf("inserting an unknown field should fail")
+	}
+	for _, value := range []string{"/a#b", "/a\nb", " /a"} {
+		if err := f.SetValue(d, value); err == nil {
+			t.Errorf("SetValue(%q) should fail", value)
+		}
+	}
+	if _, err := f.AddRule("b", false, "/"); err == nil {
+		t.Errorf("adding a rule for a missing agent should fail")
+	}
+	if got := f.String(); got != "user-agent: a\n" {
+		t.Errorf("failed edits changed file: %q", got)
+	}
+}
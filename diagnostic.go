@@ -0,0 +1,108 @@
+package robots
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Reason classifies why a line of a robots.txt file was discarded
+// by the parser.
+type Reason int
+
+const (
+	// UnknownField means the line did not begin with a field the
+	// parser recognizes.
+	UnknownField Reason = iota + 1
+	// MissingSeparator means a recognized field was not followed
+	// by a colon.
+	MissingSeparator
+	// UnexpectedText means a line continued after the point where
+	// it should have ended.
+	UnexpectedText
+	// OrphanRule means a group-member rule appeared before any
+	// user-agent line, so it applies to no agent.
+	OrphanRule
+	// EmptyAgent means a user-agent line had no value. It names no
+	// agent, but still ends the group before it.
+	EmptyAgent
+	// EmptyValue means a line that requires a value had none.
+	EmptyValue
+	// InvalidCrawlDelay means the value of a crawl-delay line was
+	// not a non-negative number of seconds.
+	InvalidCrawlDelay
+	// InvalidRequestRate means the value of a request-rate line
+	// was not a rate of the form requests/period.
+	InvalidRequestRate
+)
+
+var reasons = map[Reason]string{
+	UnknownField:       "unknown field",
+	MissingSeparator:   "missing separator",
+	UnexpectedText:     "unexpected text",
+	OrphanRule:         "rule before any user-agent",
+	EmptyAgent:         "empty user-agent",
+	EmptyValue:         "empty value",
+	InvalidCrawlDelay:  "invalid crawl-delay",
+	InvalidRequestRate: "invalid request-rate",
+}
+
+func (r Reason) String() string {
+	if s, ok := reasons[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("Reason(%d)", int(r))
+}
+
+// A Diagnostic describes a line of a robots.txt file that the parser
+// discarded, and why.
+type Diagnostic struct {
+	Line   int    // Line number, starting at 1.
+	Column int    // Column where the problem starts, in runes, starting at 1.
+	Text   string // Raw text of the line, without its line ending.
+	Reason Reason
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %v: %s", d.Line, d.Column, d.Reason, d.Text)
+}
+
+// ParseWithDiagnostics parses a robots.txt file as though it were
+// served with a 200 status code. Like From, it never fails because of
+// the content of the file. In addition to the Robots object, it
+// returns a Diagnostic for every line that was discarded, in the
+// order the lines occur. This is intended for showing the authors of
+// robots.txt files why their rules are being ignored.
+//
+// As with From, only the first 500 KiB of the file is parsed, unless
+// the SizeLimit option says otherwise. ParseWithDiagnostics will only
+// signal an error condition if it fails to read from the input at all.
+func ParseWithDiagnostics(in io.Reader, opts ...Option) (*Robots, []Diagnostic, error) {
+	c := makeConfig(opts)
+	p := newParser(newLineReader(in, c.limit()), c)
+	p.diagnose = true
+	data, err := p.run()
+	if err != nil {
+		return nil, nil, err
+	}
+	return makeRobots(200, data), p.diagnostics, nil
+}
+
+// diagnosticAt constructs a Diagnostic for the byte offset pos in
+// input, which is on the given line.
+func diagnosticAt(input string, pos, line int, reason Reason) Diagnostic {
+	start := strings.LastIndexByte(input[:pos], '\n') + 1
+	end := strings.IndexByte(input[pos:], '\n')
+	if end < 0 {
+		end = len(input)
+	} else {
+		end += pos
+	}
+	return Diagnostic{
+		Line:   line,
+		Column: utf8.RuneCountInString(input[start:pos]) + 1,
+		Text:   strings.TrimRight(input[start:end], "\r"),
+		Reason: reason,
+	}
+}
@@ -0,0 +1,115 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseWithDiagnostics(t *testing.T) {
+	input := strings.Join([]string{
+		"disallow: /orphan",  // 1
+		"user-agent:",        // 2
+		"user-agent: a",      // 3
+		"  <html>",           // 4
+		"disallow /missing",  // 5
+		"crawl-delay: soon",  // 6
+		"crawl-delay:",       // 7
+		"sitemap:",           // 8
+		"# just a comment",   // 9
+		"allow: /ok # fine",  // 10
+		"é: x",               // 11
+		"dis allow: /x",      // 12
+		"request-rate: fast", // 13
+	}, "\r\n")
+
+	var want = []Diagnostic{
+		{1, 1, "disallow: /orphan", OrphanRule},
+		{2, 1, "user-agent:", EmptyAgent},
+		{4, 3, "  <html>", UnknownField},
+		{5, 10, "disallow /missing", MissingSeparator},
+		{6, 1, "crawl-delay: soon", InvalidCrawlDelay},
+		{7, 1, "crawl-delay:", EmptyValue},
+		{8, 1, "sitemap:", EmptyValue},
+		{11, 1, "é: x", UnknownField},
+		{12, 1, "dis allow: /x", UnknownField},
+		{13, 1, "request-rate: fast", InvalidRequestRate},
+	}
+
+	r, got, err := ParseWithDiagnostics(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseWithDiagnostics returned error: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diagnostic %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if !r.Test("a", "/ok") || r.Test("a", "/missing") != true {
+		t.Errorf("diagnostics should not change how input is parsed")
+	}
+}
+
+func TestParseWithDiagnosticsPosition(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  Diagnostic
+	}{
+		{"\ufeffnope", Diagnostic{1, 1, "nope", UnknownField}},
+		{"user-agent: a\n\n\n  é  nope", Diagnostic{4, 3, "  é  nope", UnknownField}},
+		{"user-agent: a\ndisallow\nallow: /x", Diagnostic{2, 1, "disallow", MissingSeparator}},
+	}
+
+	for _, test := range tests {
+		_, got, _ := ParseWithDiagnostics(strings.NewReader(test.input))
+		if len(got) != 1 || got[0] != test.want {
+			t.Errorf("ParseWithDiagnostics(%q) = %v, want [%v]",
+				test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseEmptyAgent(t *testing.T) {
+	var tests = []struct {
+		input string
+		agent string
+		path  string
+		want  bool
+	}{
+		{"user-agent:\ndisallow: /", "crawlerbot", "/", true},
+		{"user-agent:\ndisallow: /", "", "/", true},
+		{"user-agent: a\nuser-agent:\ndisallow: /", "a", "/", false},
+		{"user-agent: a\ndisallow: /a\nuser-agent:\ndisallow: /b", "a", "/b", true},
+		{"user-agent: a\ndisallow: /a\nuser-agent:\ndisallow: /b", "a", "/a", false},
+		{"user-agent: *\ndisallow: /a\nuser-agent:\nuser-agent: b\ndisallow: /b", "b", "/b", false},
+	}
+
+	for _, test := range tests {
+		for _, opts := range [][]Option{nil, {PrefixAgentMatching()}} {
+			r, _ := From(200, strings.NewReader(test.input), opts...)
+			if got := r.Test(test.agent, test.path); got != test.want {
+				t.Errorf("%q with options %d: Test(%q, %q) = %t, want %t",
+					test.input, len(opts), test.agent, test.path, got, test.want)
+			}
+			for _, g := range r.Groups() {
+				for _, name := range g.Agents {
+					if name == "" {
+						t.Errorf("%q: empty agent kept in %+v", test.input, g)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	r, diags, err := ParseWithDiagnostics(strings.NewReader(""))
+	if err != nil || len(diags) != 0 {
+		t.Errorf("empty input: got %v, %v", diags, err)
+	}
+	if !r.Test("Crawlerbot", "/") {
+		t.Errorf("empty input should allow everything")
+	}
+}
@@ -1,6 +1,7 @@
 package robots_test
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/benjaminestes/robots"
@@ -49,3 +50,38 @@ func ExampleRobots() {
 		}
 	}
 }
+
+func ExampleBuilder() {
+	r, text, err := robots.NewBuilder().
+		Group("Googlebot", "Bingbot").
+		Disallow("/private").
+		Allow("/private/ok").
+		Sitemap("https://www.example.com/sitemap.xml").
+		Build()
+	if err != nil {
+		// Handle error - the policy is invalid.
+	}
+
+	fmt.Print(text)
+	fmt.Println(r.Test("Googlebot", "/private/ok"))
+	// Output:
+	// User-agent: Googlebot
+	// User-agent: Bingbot
+	// Disallow: /private
+	// Allow: /private/ok
+	//
+	// Sitemap: https://www.example.com/sitemap.xml
+	// true
+}
+
+func ExampleNormalizePattern() {
+	for _, pattern := range []string{"/fish**.php", "/fish*", "/*.php*$", "/price$list", "**"} {
+		fmt.Println(robots.NormalizePattern(pattern))
+	}
+	// Output:
+	// /fish*.php
+	// /fish
+	// /*.php
+	// /price$list
+	// *
+}
@@ -0,0 +1,173 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// maxRedirects is the number of redirects followed when fetching a
+// robots.txt file. Both Google's specification and RFC 9309 require at
+// least five to be followed.
+const maxRedirects = 5
+
+// A Fetcher retrieves robots.txt files over HTTP and interprets the
+// response according to the specification:
+//
+// Up to five redirects are followed, including redirects to other
+// hosts. If there are more, the robots.txt file is treated as
+// unavailable, and all URLs are allowed.
+//
+// A 4xx status code means that all URLs are allowed. A 5xx status
+// code, a 429 (Too Many Requests) status code, or a failure to connect
+// or read the response, means that all URLs are disallowed.
+//
+// The response is interpreted by FromResponse; only the first
+// 500 KiB of the file is read, unless Options include SizeLimit.
+//
+// The zero value of a Fetcher is ready to use.
+type Fetcher struct {
+	// Client is used to make requests. If nil,
+	// http.DefaultClient is used. Its CheckRedirect function is
+	// not used: the Fetcher has its own redirect policy.
+	Client *http.Client
+
+	// If UserAgent is not empty, it is sent as the User-Agent
+	// header of each request.
+	UserAgent string
+
+	// Options are passed to From when parsing each file.
+	Options []Option
+}
+
+// A Result is a Robots object retrieved by a Fetcher, along with how
+// it was retrieved.
+type Result struct {
+	*Robots
+
+	URL        string      // URL of the robots.txt file requested.
+	FinalURL   string      // URL the response came from, after redirects.
+	StatusCode int         // Status code of the response, or 0 if there was none.
+	Header     http.Header // Header of the response, or nil if there was none.
+	Fetched    time.Time   // Time the response was received.
+
+	// ETag and LastModified are the validators of the response,
+	// sent when the file is revalidated. They are empty if the
+	// server sent none.
+	ETag         string
+	LastModified string
+
+	// Expires is the time after which a Cache fetches the file
+	// again. It is zero for results that were not cached.
+	Expires time.Time
+
+	// Err is the error that prevented the file from being
+	// retrieved, if any. In that case, the Robots object
+	// disallows all URLs.
+	Err error
+}
+
+// Fetch retrieves the robots.txt file that governs rawurl, which can
+// be any absolute URL in its scope; see Locate.
+//
+// Failing to retrieve the file is not an error: it is reported in the
+// Err field of the result, and the resulting Robots object disallows
+// all URLs. Fetch only returns an error if rawurl is invalid. The
+// request is cancelled if ctx is done before it completes.
+func (f *Fetcher) Fetch(ctx context.Context, rawurl string) (*Result, error) {
+	robotsURL, err := Locate(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return f.fetch(ctx, robotsURL, nil)
+}
+
+// Revalidate retrieves the robots.txt file of prev again, if it has
+// changed. The request is conditional on the ETag and LastModified of
+// prev. If the server responds that the file has not changed, with
+// status code 304, the result shares the Robots object of prev;
+// otherwise, Revalidate behaves like Fetch.
+func (f *Fetcher) Revalidate(ctx context.Context, prev *Result) (*Result, error) {
+	return f.fetch(ctx, prev.URL, prev)
+}
+
+// robotsFetchKey marks the context of a request for a robots.txt file,
+// which its redirects share, so that a Transport lets them through.
+type robotsFetchKey struct{}
+
+// fetch retrieves the file at robotsURL. If prev is not nil, the
+// request is conditional on its validators.
+func (f *Fetcher) fetch(ctx context.Context, robotsURL string, prev *Result) (*Result, error) {
+	req, err := http.NewRequest("GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(context.WithValue(ctx, robotsFetchKey{}, true))
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	if prev != nil && prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev != nil && prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+
+	res := &Result{
+		URL:      robotsURL,
+		FinalURL: robotsURL,
+	}
+	resp, err := f.client().Do(req)
+	res.Fetched = time.Now()
+	if err != nil {
+		res.fail(err, f.Options)
+		return res, nil
+	}
+	res.FinalURL = resp.Request.URL.String()
+	res.StatusCode = resp.StatusCode
+	res.Header = resp.Header
+	res.ETag = resp.Header.Get("ETag")
+	res.LastModified = resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		resp.Body.Close()
+		res.Robots = prev.Robots
+		// A 304 response need not repeat the validators.
+		if res.ETag == "" {
+			res.ETag = prev.ETag
+		}
+		if res.LastModified == "" {
+			res.LastModified = prev.LastModified
+		}
+		return res, nil
+	}
+	res.Robots, err = FromResponse(resp, f.Options...)
+	if err != nil {
+		res.fail(err, f.Options)
+	}
+	return res, nil
+}
+
+// fail records that res could not be retrieved because of err.
+func (res *Result) fail(err error, opts []Option) {
+	res.Err = err
+	res.Robots, _ = From(http.StatusServiceUnavailable, nil, opts...)
+}
+
+// client returns a copy of the client of f with the redirect policy
+// of a Fetcher.
+func (f *Fetcher) client() *http.Client {
+	c := http.DefaultClient
+	if f.Client != nil {
+		c = f.Client
+	}
+	client := *c
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			// Stop and use the redirect response itself,
+			// which allows all URLs.
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	return &client
+}
@@ -0,0 +1,228 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		fmt.Fprint(w, "user-agent: *\ndisallow: /private\nsitemap: http://example.com/s.xml\n")
+	}))
+	defer ts.Close()
+
+	f := &Fetcher{UserAgent: "Crawlerbot/1.0"}
+	res, err := f.Fetch(context.Background(), ts.URL+"/some/page.html")
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if res.Err != nil {
+		t.Errorf("res.Err = %v", res.Err)
+	}
+	if res.URL != ts.URL+"/robots.txt" || res.FinalURL != res.URL {
+		t.Errorf("res.URL = %q, res.FinalURL = %q", res.URL, res.FinalURL)
+	}
+	if res.StatusCode != 200 || res.Fetched.IsZero() {
+		t.Errorf("res.StatusCode = %d, res.Fetched = %v", res.StatusCode, res.Fetched)
+	}
+	if res.Test("Crawlerbot", "/private") || !res.Test("Crawlerbot", "/public") {
+		t.Errorf("fetched rules were not applied")
+	}
+	if got := res.Sitemaps(); len(got) != 1 {
+		t.Errorf("res.Sitemaps() = %v", got)
+	}
+	if userAgent != "Crawlerbot/1.0" {
+		t.Errorf("request User-Agent = %q", userAgent)
+	}
+}
+
+func TestFetchStatus(t *testing.T) {
+	var tests = []struct {
+		status int
+		want   bool
+	}{
+		{200, false},
+		{401, true},
+		{403, true},
+		{404, true},
+		{500, false},
+		{503, false},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, "user-agent: *\ndisallow: /\n")
+		}))
+		res, _ := (&Fetcher{}).Fetch(context.Background(), ts.URL)
+		ts.Close()
+		if res.StatusCode != test.status {
+			t.Errorf("res.StatusCode = %d, want %d", res.StatusCode, test.status)
+		}
+		if got := res.Test("Crawlerbot", "/"); got != test.want {
+			t.Errorf("status %d: res.Test = %t, want %t", test.status, got, test.want)
+		}
+	}
+}
+
+func TestFetchRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: *\ndisallow: /\n")
+	}))
+	defer target.Close()
+
+	// hops redirects n times within its own host, then to target.
+	hops := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Query().Get("n"), "%d", &n)
+		if n <= 1 {
+			http.Redirect(w, r, target.URL+"/robots.txt", http.StatusMovedPermanently)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/robots.txt?n=%d", n-1), http.StatusFound)
+	}))
+	defer hops.Close()
+
+	var tests = []struct {
+		redirects int
+		want      bool
+	}{
+		{1, false},
+		{5, false},
+		{6, true},
+	}
+
+	for _, test := range tests {
+		// Start the fetcher at the first hop, bypassing Locate.
+		client := &http.Client{Transport: &rewriteTransport{
+			from: hops.URL + "/robots.txt",
+			to:   fmt.Sprintf("%s/robots.txt?n=%d", hops.URL, test.redirects),
+		}}
+		res, _ := (&Fetcher{Client: client}).Fetch(context.Background(), hops.URL)
+		if got := res.Test("Crawlerbot", "/"); got != test.want {
+			t.Errorf("%d redirects: res.Test = %t, want %t", test.redirects, got, test.want)
+		}
+		if test.want == false && res.FinalURL != target.URL+"/robots.txt" {
+			t.Errorf("%d redirects: res.FinalURL = %q", test.redirects, res.FinalURL)
+		}
+	}
+}
+
+// rewriteTransport replaces requests for one URL with another.
+type rewriteTransport struct {
+	from, to string
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.String() == rt.from {
+		req = req.Clone(req.Context())
+		req.URL, _ = req.URL.Parse(rt.to)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: *\n")
+		fmt.Fprint(w, strings.Repeat("# padding\n", defaultSizeLimit/10))
+		fmt.Fprint(w, "disallow: /\n")
+	}))
+	defer ts.Close()
+
+	res, _ := (&Fetcher{}).Fetch(context.Background(), ts.URL)
+	if !res.Test("Crawlerbot", "/") {
+		t.Errorf("rule after %d bytes should be ignored", defaultSizeLimit)
+	}
+}
+
+func TestFetchUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	res, err := (&Fetcher{}).Fetch(context.Background(), url)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if res.Err == nil || res.StatusCode != 0 {
+		t.Errorf("res.Err = %v, res.StatusCode = %d", res.Err, res.StatusCode)
+	}
+	if res.Test("Crawlerbot", "/") {
+		t.Errorf("unreachable robots.txt should disallow all")
+	}
+}
+
+func TestFetchDeadline(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res, _ := (&Fetcher{}).Fetch(ctx, ts.URL)
+	if res.Err == nil {
+		t.Errorf("expected error after deadline")
+	}
+	if res.Test("Crawlerbot", "/") {
+		t.Errorf("robots.txt not retrieved before deadline should disallow all")
+	}
+}
+
+func TestFetchInvalidURL(t *testing.T) {
+	if _, err := (&Fetcher{}).Fetch(context.Background(), "/relative"); err == nil {
+		t.Errorf("Fetch of relative URL should fail")
+	}
+}
+
+func TestFetchRevalidate(t *testing.T) {
+	const lastModified = "Wed, 01 Jan 2020 00:00:00 GMT"
+	etag := `"v1"`
+	var conditional []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		fmt.Fprintf(w, "user-agent: *\ndisallow: /%s\n", strings.Trim(etag, `"`))
+	}))
+	defer ts.Close()
+
+	f := &Fetcher{}
+	ctx := context.Background()
+	first, _ := f.Fetch(ctx, ts.URL)
+	if first.ETag != etag || first.LastModified != lastModified {
+		t.Fatalf("validators = %q, %q", first.ETag, first.LastModified)
+	}
+
+	second, _ := f.Revalidate(ctx, first)
+	if second.StatusCode != http.StatusNotModified || second.Robots != first.Robots {
+		t.Errorf("unchanged file: status %d, same Robots %t", second.StatusCode, second.Robots == first.Robots)
+	}
+	if second.ETag != etag || second.LastModified != lastModified {
+		t.Errorf("validators not kept: %q, %q", second.ETag, second.LastModified)
+	}
+
+	etag = `"v2"`
+	third, _ := f.Revalidate(ctx, second)
+	if third.StatusCode != http.StatusOK || third.Test("Crawlerbot", "/v2") {
+		t.Errorf("changed file not used: status %d", third.StatusCode)
+	}
+
+	want := []string{"|", `"v1"|` + lastModified, `"v1"|` + lastModified}
+	if fmt.Sprint(conditional) != fmt.Sprint(want) {
+		t.Errorf("conditional headers = %q, want %q", conditional, want)
+	}
+}
@@ -0,0 +1,109 @@
+package robots
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A FileStore is a Store that keeps results in files in a directory,
+// so that a Cache using it survives restarts. Each result is kept in
+// its own file, named after a hash of its key.
+//
+// Files that cannot be read as results, such as those written in an
+// older version of the format, are treated as missing and removed.
+type FileStore struct {
+	// Dir is the directory holding the files. It is created if it
+	// does not exist.
+	Dir string
+
+	// Clock is used to tell whether results have expired. If nil,
+	// the time package is used.
+	Clock Clock
+}
+
+// fileEntry is the content of a file of a FileStore.
+type fileEntry struct {
+	Key     string          `json:"key"`
+	Expires time.Time       `json:"expires"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// Get implements Store.
+func (s *FileStore) Get(key string) (*Result, error) {
+	name := s.path(key)
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e fileEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return nil, s.remove(name)
+	}
+	if !clockNow(s.Clock).Before(e.Expires) {
+		return nil, s.remove(name)
+	}
+	res := &Result{}
+	if err := res.UnmarshalBinary(e.Result); err != nil {
+		return nil, s.remove(name)
+	}
+	return res, nil
+}
+
+// Put implements Store.
+func (s *FileStore) Put(key string, res *Result, expires time.Time) error {
+	data, err := res.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(fileEntry{
+		Key:     key,
+		Expires: expires,
+		Result:  data,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a reader never sees
+	// a partly written file.
+	f, err := ioutil.TempFile(s.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(key string) error {
+	return s.remove(s.path(key))
+}
+
+func (s *FileStore) remove(name string) error {
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(key))))
+}
@@ -0,0 +1,111 @@
+package robots
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tempStore(t *testing.T) (*FileStore, func()) {
+	dir, err := ioutil.TempDir("", "robots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &FileStore{Dir: filepath.Join(dir, "cache")}, func() { os.RemoveAll(dir) }
+}
+
+func TestFileStore(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+	const key = "https://example.com/robots.txt"
+
+	if res, err := s.Get(key); res != nil || err != nil {
+		t.Errorf("Get of missing key = %v, %v", res, err)
+	}
+
+	r, _ := From(200, strings.NewReader("user-agent: *\ndisallow: /private\n"))
+	if err := s.Put(key, &Result{Robots: r, URL: key}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	res, err := s.Get(key)
+	if err != nil || res == nil {
+		t.Fatalf("Get = %v, %v", res, err)
+	}
+	if res.URL != key || res.Test("Crawlerbot", "/private") {
+		t.Errorf("stored result not returned: %+v", res)
+	}
+	if res, _ := s.Get("https://example.org/robots.txt"); res != nil {
+		t.Errorf("Get of other key returned %+v", res)
+	}
+
+	if err := s.Delete(key); err != nil {
+		t.Errorf("Delete returned error: %v", err)
+	}
+	if res, _ := s.Get(key); res != nil {
+		t.Errorf("Get after Delete returned %+v", res)
+	}
+	if err := s.Delete(key); err != nil {
+		t.Errorf("Delete of missing key returned error: %v", err)
+	}
+
+	s.Put(key, &Result{Robots: r, URL: key}, time.Now().Add(-time.Second))
+	if res, _ := s.Get(key); res != nil {
+		t.Errorf("Get of expired result returned %+v", res)
+	}
+	if _, err := os.Stat(s.path(key)); !os.IsNotExist(err) {
+		t.Errorf("expired file not removed")
+	}
+}
+
+func TestFileStoreVersion(t *testing.T) {
+	s, cleanup := tempStore(t)
+	defer cleanup()
+	const key = "https://example.com/robots.txt"
+
+	// An entry written in an unsupported version of the format is
+	// treated as missing.
+	r, _ := From(200, strings.NewReader("user-agent: *\ndisallow: /\n"))
+	s.Put(key, &Result{Robots: r, URL: key}, time.Now().Add(time.Hour))
+	data, err := ioutil.ReadFile(s.path(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := strings.Replace(string(data), `"v":2`, `"v":1`, -1)
+	if old == string(data) {
+		t.Fatalf("no version found in %s", data)
+	}
+	ioutil.WriteFile(s.path(key), []byte(old), 0644)
+
+	if res, err := s.Get(key); res != nil || err != nil {
+		t.Errorf("Get of old version = %v, %v", res, err)
+	}
+	if _, err := os.Stat(s.path(key)); !os.IsNotExist(err) {
+		t.Errorf("old version not removed")
+	}
+}
+
+func TestCacheFileStore(t *testing.T) {
+	srv := newRobotsServer()
+	defer srv.Close()
+	s, cleanup := tempStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	c := &Cache{Store: s}
+	if ok, _ := c.Allowed(ctx, "Crawlerbot", srv.URL+"/private"); ok {
+		t.Errorf("/private allowed")
+	}
+
+	// A new cache, as after a restart, uses the stored file.
+	c = &Cache{Store: s}
+	if ok, _ := c.Allowed(ctx, "Crawlerbot", srv.URL+"/private"); ok {
+		t.Errorf("/private allowed after restart")
+	}
+	if srv.requests != 1 {
+		t.Errorf("got %d requests, want 1", srv.requests)
+	}
+}
@@ -0,0 +1,80 @@
+package robots
+
+import (
+	"strings"
+	"time"
+)
+
+// A Group is a group of rules as it appears in a robots.txt file: the
+// agents named by its user-agent lines and the rules that follow
+// them.
+//
+// A Group is a copy. Modifying it does not change how the Robots
+// object it came from matches URLs.
+type Group struct {
+	Agents []string
+	Rules  []Rule
+
+	// If HasCrawlDelay is true, the group declared the crawl
+	// delay CrawlDelay in a crawl-delay line.
+	CrawlDelay    time.Duration
+	HasCrawlDelay bool
+
+	// RequestRate is the value of the request-rate line of the
+	// group as written, such as "1/5", or "" if it has none.
+	RequestRate string
+}
+
+// A Rule is an allow or disallow record within a group.
+type Rule struct {
+	Allow   bool
+	Pattern string // The path pattern as written.
+	Line    int    // Line of the robots.txt file, starting at 1.
+}
+
+// delay returns the longest delay between requests that g declares,
+// by crawl-delay or request-rate, and whether it declares any.
+func (g *Group) delay() (time.Duration, bool) {
+	d, ok := g.CrawlDelay, g.HasCrawlDelay
+	if rate, valid := parseRate(g.RequestRate); valid && (!ok || rate > d) {
+		d, ok = rate, true
+	}
+	return d, ok
+}
+
+// copy returns a deep copy of g.
+func (g *Group) copy() Group {
+	c := *g
+	c.Agents = append([]string(nil), g.Agents...)
+	c.Rules = append([]Rule(nil), g.Rules...)
+	return c
+}
+
+// Groups returns the groups of rules in r, in the order they appear
+// in the robots.txt file.
+func (r *Robots) Groups() []Group {
+	groups := make([]Group, len(r.groups))
+	for i, g := range r.groups {
+		groups[i] = g.copy()
+	}
+	return groups
+}
+
+// Agents returns the names of the agents in r, in the order they
+// first appear in the robots.txt file. Names are compared without
+// regard to case, so an agent named twice is only reported once.
+func (r *Robots) Agents() []string {
+	var agents []string
+	seen := map[string]bool{}
+	for _, g := range r.groups {
+		for _, name := range g.Agents {
+			key := strings.ToLower(name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			agents = append(agents, name)
+		}
+	}
+	return agents
+}
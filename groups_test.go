@@ -0,0 +1,73 @@
+package robots
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroups(t *testing.T) {
+	input := strings.Join([]string{
+		"disallow: /orphan",
+		"user-agent: b",
+		"disallow: /z",
+		"allow: /a",
+		"disallow:",
+		"sitemap: http://example.com/sitemap.xml",
+		"user-agent: A",
+		"user-agent: c",
+		"crawl-delay: 3",
+		"allow: /",
+		"user-agent: a",
+	}, "\n")
+
+	want := []Group{
+		{
+			Agents: []string{"b"},
+			Rules: []Rule{
+				{Allow: false, Pattern: "/z", Line: 3},
+				{Allow: true, Pattern: "/a", Line: 4},
+			},
+		},
+		{
+			Agents:        []string{"A", "c"},
+			Rules:         []Rule{{Allow: true, Pattern: "/", Line: 10}},
+			CrawlDelay:    3 * time.Second,
+			HasCrawlDelay: true,
+		},
+		{
+			Agents: []string{"a"},
+		},
+	}
+
+	r, _ := From(200, strings.NewReader(input))
+	if got := r.Groups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("r.Groups() = %+v, want %+v", got, want)
+	}
+	if got, want := r.Agents(), []string{"b", "A", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("r.Agents() = %v, want %v", got, want)
+	}
+}
+
+func TestGroupsImmutable(t *testing.T) {
+	fname := "testdata/grouping.txt"
+	data, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("couldn't open test data %s", fname)
+	}
+	r, _ := From(200, data)
+
+	groups := r.Groups()
+	groups[0].Agents[0] = "z"
+	groups[0].Rules[0].Allow = true
+	groups[0].Rules[0].Pattern = "/"
+
+	if got := r.Groups(); got[0].Agents[0] != "a" || got[0].Rules[0].Pattern != "/c" {
+		t.Errorf("modifying result of r.Groups() changed r: %+v", got[0])
+	}
+	if r.Test("a", "/c") {
+		t.Errorf("modifying result of r.Groups() changed matching")
+	}
+}
@@ -0,0 +1,123 @@
+package robots
+
+import (
+	"math"
+	"strings"
+)
+
+// A ruleIndex finds the first member of a group to match a path, as a
+// sequential scan of the members would, without testing every member.
+//
+// Members are stored in a radix trie under the first literal segment
+// of their patterns. Walking the trie along a path reaches exactly the
+// members whose first segment begins the path. A member without
+// wildcards matches when it is reached, if it is not anchored, or at
+// the end of the path, if it is. So for members without wildcards,
+// finding a match takes time roughly proportional to the length of the
+// path, however many members there are. A member with wildcards is
+// tested when it is reached, unless a member of lower rank has already
+// matched.
+type ruleIndex struct {
+	root trieNode
+}
+
+// An indexEntry is a member and its position in the sequence of
+// members of its group. The member with the lowest rank is the one a
+// sequential scan finds first.
+type indexEntry struct {
+	m    *member
+	rank int
+}
+
+type trieNode struct {
+	label    string // text of the edge leading to the node
+	children map[byte]*trieNode
+	prefix   indexEntry   // first unanchored member without wildcards
+	exact    indexEntry   // first anchored member without wildcards
+	wild     []indexEntry // members with wildcards, by rank
+}
+
+func newRuleIndex(members []*member) *ruleIndex {
+	x := &ruleIndex{}
+	for rank, m := range members {
+		segments := m.pattern.segments
+		n := x.root.insert(segments[0])
+		e := indexEntry{m, rank}
+		switch {
+		case len(segments) > 1:
+			n.wild = append(n.wild, e)
+		case m.pattern.anchored:
+			if n.exact.m == nil {
+				n.exact = e
+			}
+		default:
+			if n.prefix.m == nil {
+				n.prefix = e
+			}
+		}
+	}
+	return x
+}
+
+// insert returns the node for key below n, adding it if necessary.
+func (n *trieNode) insert(key string) *trieNode {
+	for key != "" {
+		child := n.children[key[0]]
+		if child == nil {
+			if n.children == nil {
+				n.children = map[byte]*trieNode{}
+			}
+			child = &trieNode{label: key}
+			n.children[key[0]] = child
+			return child
+		}
+		l := 0
+		for l < len(key) && l < len(child.label) && key[l] == child.label[l] {
+			l++
+		}
+		if l < len(child.label) {
+			// Split the edge where key leaves it.
+			mid := &trieNode{
+				label:    child.label[:l],
+				children: map[byte]*trieNode{child.label[l]: child},
+			}
+			child.label = child.label[l:]
+			n.children[key[0]] = mid
+			child = mid
+		}
+		n, key = child, key[l:]
+	}
+	return n
+}
+
+// find returns the member of lowest rank that matches path.
+func (x *ruleIndex) find(path string) (*member, bool) {
+	best := indexEntry{rank: math.MaxInt32}
+	n, i := &x.root, 0
+	for {
+		if n.prefix.m != nil && n.prefix.rank < best.rank {
+			best = n.prefix
+		}
+		if i == len(path) && n.exact.m != nil && n.exact.rank < best.rank {
+			best = n.exact
+		}
+		for _, e := range n.wild {
+			if e.rank >= best.rank {
+				break
+			}
+			if e.m.pattern.match(path) {
+				best = e
+				break
+			}
+		}
+		if i == len(path) {
+			break
+		}
+		n = n.children[path[i]]
+		if n == nil || !strings.HasPrefix(path[i:], n.label) {
+			break
+		}
+		i += len(n.label)
+	}
+	return best.m, best.m != nil
+}
@@ -0,0 +1,90 @@
+package robots
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRuleIndex(t *testing.T) {
+	var tests = []struct {
+		rules string
+		path  string
+		want  string // pattern of the matching rule, or "-" for none
+	}{
+		{"disallow: /a\nallow: /ab", "/abc", "/ab"},
+		{"disallow: /ab\nallow: /a", "/a", "/a"},
+		{"disallow: /a\nallow: /a", "/a", "/a"},
+		{"disallow: /a$\nallow: /a", "/ab", "/a"},
+		{"allow: /a\ndisallow: /a$", "/a", "/a$"},
+		{"allow: /a\ndisallow: /a$", "/ab", "/a"},
+		{"disallow: /*.php\nallow: /x", "/x.php", "/*.php"},
+		{"disallow: /*.php\nallow: /xyzab", "/xyzab.php", "/xyzab"},
+		{"disallow: /a*z\ndisallow: /abc*x", "/abcxz", "/abc*x"},
+		{"disallow: /a*z\ndisallow: /abc*x", "/abcz", "/a*z"},
+		{"disallow: /a$b", "/a", "-"},
+		{"disallow: /a$b", "/a$b", "/a$b"},
+		{"disallow: /a*\nallow: /a**", "/ab", "/a**"},
+		{"allow: /a**\ndisallow: /a*", "/ab", "/a**"},
+		{"disallow: /\nallow: /$", "/", "/$"},
+		{"disallow: /\nallow: /$", "/page", "/"},
+		{"disallow: *", "", "*"},
+	}
+
+	for _, test := range tests {
+		r, _ := From(200, strings.NewReader("user-agent: *\n"+test.rules))
+		g := r.agents[0].group
+		if g.index == nil {
+			t.Fatalf("%q: group not indexed", test.rules)
+		}
+		got := "-"
+		if m, ok := g.find(test.path); ok {
+			got = m.path
+		}
+		if got != test.want {
+			t.Errorf("%q: rule matching %q = %q, want %q", test.rules, test.path, got, test.want)
+		}
+	}
+}
+
+// TestRuleIndexScan checks that the index finds the same member as a
+// sequential scan, for random groups of rules and paths.
+func TestRuleIndexScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(alphabet string, n int) string {
+		b := make([]byte, rng.Intn(n+1))
+		for i := range b {
+			b[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+
+	for _, c := range []config{{}, {rfc9309: true}} {
+		for i := 0; i < 500; i++ {
+			var g group
+			for n := rng.Intn(30); n >= 0; n-- {
+				g.addMember(&member{
+					allow: rng.Intn(2) == 0,
+					path:  "/" + random("ab/*$é", 6),
+				}, c)
+			}
+			g.buildIndex()
+			for j := 0; j < 50; j++ {
+				path := "/" + random("ab/$", 10)
+				got, _ := g.find(path)
+				want, _ := g.scan(path)
+				if got != want {
+					t.Fatalf("find(%q) = %+v, scan gives %+v", path, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRuleIndexMerged(t *testing.T) {
+	r, _ := From(200, strings.NewReader(
+		"user-agent: a\ndisallow: /x\n\nuser-agent: b\nallow: /\n\nuser-agent: a\nallow: /xy\n"))
+	if r.Test("a", "/x") || !r.Test("a", "/xy") {
+		t.Errorf("index does not reflect rules of merged groups")
+	}
+}
@@ -3,6 +3,9 @@
 // full specification at:
 // https://developers.google.com/search/reference/robots_txt.
 //
+// Google's specification has since been standardized as RFC 9309. To
+// follow the RFC where it differs, pass the RFC9309 option to From.
+//
 // What clients need to think about
 //
 // Clients of this package have one obligation: when testing whether a
@@ -19,7 +22,8 @@
 // accessible would be: a) Locate the robots.txt file for the URL; b)
 // check whether you have fetched data for that robots.txt file; c) if
 // yes, use the data to Test the URL against your user agent; d) if
-// no, fetch the robots.txt data and try again.
+// no, fetch the robots.txt data and try again. A Cache does all of
+// this: its Allowed method is the only call a crawler needs.
 //
 // For details, see "File location & range of validity" in the
 // specification:
@@ -29,7 +33,8 @@
 //
 // A generous parser is specified. A valid line is accepted, and an
 // invalid line is silently discarded. This is true even if the
-// content parsed is in an unexpected format, like HTML.
+// content parsed is in an unexpected format, like HTML. To find out
+// which lines were discarded, and why, use ParseWithDiagnostics.
 //
 // For details, see "File format" in the specification:
 // https://developers.google.com/search/reference/robots_txt#file-format
@@ -40,8 +45,13 @@
 // accessible, even if there is no robots.txt file, or the body of the
 // robots.txt file is empty.  So a robots.txt file with a 404 status
 // code will result in all URLs being crawlable.  The exception to
-// this is a 5xx status code. This is treated as a temporary "full
-// disallow" of crawling.
+// this is a 5xx status code, or a 429 (Too Many Requests) status
+// code, which Google also treats as a server error. This is treated as
+// a temporary "full disallow" of crawling.
+//
+// FromResponse applies these rules to an *http.Response, along with
+// redirects and HTML error pages served with a 200 status code. The
+// Status method of the result reports which rule applied.
 //
 // For details, see "Handling HTTP result codes" in the specification:
 // https://developers.google.com/search/reference/robots_txt#handling-http-result-codes
@@ -50,7 +60,6 @@ package robots
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"strings"
 
@@ -67,20 +76,22 @@ import (
 // errors: all valid input is accepted, and invalid input is silently
 // rejected without failing. Therefore, From will only signal an error
 // condition if it fails to read from the input at all.
-func From(status int, in io.Reader) (*Robots, error) {
-	switch in.(type) {
-	case nil:
-		return makeRobots(status, nil), nil
+//
+// The file is read a line at a time, and only its first 500 KiB is
+// parsed; see SizeLimit. The body of a response with a status code
+// other than 2xx is not read.
+//
+// Options change how the file is interpreted; see RFC9309,
+// PrefixAgentMatching and FullUserAgents.
+func From(status int, in io.Reader, opts ...Option) (*Robots, error) {
+	c := makeConfig(opts)
+	if in == nil || status < 200 || status >= 300 {
+		return makeRobots(status, &robotsdata{config: c}), nil
 	}
-
-	buf, err := ioutil.ReadAll(in)
+	data, err := parseReader(in, c)
 	if err != nil {
 		return nil, err
 	}
-	var data *robotsdata
-	if status >= 200 && status < 300 {
-		data = parse(string(buf))
-	}
 	return makeRobots(status, data), nil
 }
 
@@ -1,7 +1,6 @@
 package robots
 
 import (
-	"fmt"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -12,6 +11,11 @@ import (
 // his work.
 //
 // https://www.youtube.com/watch?v=HxaD_trXwRE
+//
+// As in the lexer of text/template, the state functions do not run in
+// a goroutine of their own. The consumer pulls items with nextItem,
+// which runs state functions until one emits an item. No state
+// function emits more than one.
 
 type membertype int
 
@@ -21,33 +25,60 @@ const (
 	itemDisallow
 	itemAllow
 	itemSitemap
+	itemCrawlDelay
+	itemRequestRate
 )
 
 const eof = -1
 
 var membertypes = map[string]membertype{
-	"user-agent": itemUserAgent,
-	"disallow":   itemDisallow,
-	"allow":      itemAllow,
-	"sitemap":    itemSitemap,
+	"user-agent":   itemUserAgent,
+	"disallow":     itemDisallow,
+	"allow":        itemAllow,
+	"sitemap":      itemSitemap,
+	"crawl-delay":  itemCrawlDelay,
+	"request-rate": itemRequestRate,
 }
 
+// An item is a single field and its value. Its position is that of
+// the start of the field, so that it can be reported in
+// diagnostics. An item of type itemError carries the reason for the
+// error, and no value.
 type item struct {
-	typ membertype
-	val string
+	typ    membertype
+	val    string
+	pos    int // byte offset of the item in the input
+	vpos   int // byte offset of val in the input
+	line   int // line number of the item, starting at 1
+	reason Reason
 }
 
 type lexer struct {
-	typ   membertype
-	input string
-	start int
-	pos   int
-	width int
-	items chan *item
+	typ       membertype
+	input     string
+	start     int
+	pos       int
+	width     int
+	line      int // line number of pos, starting at 1
+	fieldPos  int // byte offset of the field being lexed
+	fieldLine int // line number of fieldPos
+	valuePos  int // byte offset of the value being lexed
+	state     lexfn
+	item      item // the item emitted by the last state function
+	ready     bool // whether item has yet to be returned by nextItem
 }
 
-func (l *lexer) nextItem() *item {
-	return <-l.items
+// nextItem returns the next item of the input, and false if there are
+// no more.
+func (l *lexer) nextItem() (item, bool) {
+	for !l.ready {
+		if l.state == nil {
+			return item{}, false
+		}
+		l.state = l.state(l)
+	}
+	l.ready = false
+	return l.item, true
 }
 
 func (l *lexer) next() rune {
@@ -58,11 +89,17 @@ func (l *lexer) next() rune {
 	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
 	l.width = w
 	l.pos += w
+	if r == '\n' {
+		l.line++
+	}
 	return r
 }
 
 func (l *lexer) backup() {
 	l.pos -= l.width
+	if l.width == 1 && l.input[l.pos] == '\n' {
+		l.line--
+	}
 }
 
 func (l *lexer) peek() rune {
@@ -72,10 +109,14 @@ func (l *lexer) peek() rune {
 }
 
 func (l *lexer) emit() {
-	l.items <- &item{
-		typ: l.typ,
-		val: strings.TrimRightFunc(l.input[l.start:l.pos], unicode.IsSpace),
+	l.item = item{
+		typ:  l.typ,
+		val:  strings.TrimRightFunc(l.input[l.start:l.pos], unicode.IsSpace),
+		pos:  l.fieldPos,
+		vpos: l.valuePos,
+		line: l.fieldLine,
 	}
+	l.ready = true
 	l.start = l.pos
 }
 
@@ -87,17 +128,23 @@ func (l *lexer) ignore() {
 // https://developers.google.com/search/reference/robots_txt#abstract,
 // simply accept lines that are valid and silently discard those that
 // are not (even if received content is HTML).
-func (l *lexer) errorf(format string, args ...interface{}) {
-	l.items <- &item{
-		typ: itemError,
-		val: fmt.Sprintf(format, args...),
+//
+// The error is positioned at the start of the unconsumed input,
+// unless that has moved past the line on which the field began. In
+// that case it is positioned at the field, since that is the line
+// being discarded.
+func (l *lexer) error(reason Reason) {
+	pos, line := l.start, l.line
+	if line != l.fieldLine {
+		pos, line = l.fieldPos, l.fieldLine
 	}
-}
-
-func (l *lexer) run() {
-	for fn := lexStart; fn != nil; fn = fn(l) {
+	l.item = item{
+		typ:    itemError,
+		pos:    pos,
+		line:   line,
+		reason: reason,
 	}
-	close(l.items)
+	l.ready = true
 }
 
 func stripBOM(s string) string {
@@ -107,17 +154,21 @@ func stripBOM(s string) string {
 	return s
 }
 
-func lex(in string) []*item {
-	l := &lexer{
-		input: stripBOM(in),
-		items: make(chan *item),
-	}
-	go l.run()
-	items := []*item{}
-	for item := l.nextItem(); item != nil; item = l.nextItem() {
-		items = append(items, item)
+// lex returns a lexer of in. Its items are read with nextItem.
+func lex(in string) *lexer {
+	l := &lexer{}
+	l.reset(in, 1)
+	return l
+}
+
+// reset makes l a lexer of in, which begins on the given line, so that
+// one lexer can be used for each chunk of a file in turn.
+func (l *lexer) reset(in string, line int) {
+	*l = lexer{
+		input: in,
+		line:  line,
+		state: lexStart,
 	}
-	return items
 }
 
 type lexfn func(*lexer) lexfn
@@ -138,6 +189,8 @@ func lexStart(l *lexer) lexfn {
 }
 
 func lexField(l *lexer) lexfn {
+	l.fieldPos = l.start
+	l.fieldLine = l.line
 	for field, typ := range membertypes {
 		if len(l.input[l.start:]) < len(field) {
 			// The remaining input is shorter than the
@@ -152,7 +205,7 @@ func lexField(l *lexer) lexfn {
 		}
 	}
 	// The input did not match a field. We emit an error and continue.
-	l.errorf("unexpected field type: %s", l.input[l.start:])
+	l.error(UnknownField)
 	return lexNextLine
 }
 
@@ -166,14 +219,27 @@ func lexNextLine(l *lexer) lexfn {
 	return lexStart
 }
 
-// Check for a separator, optionally with LWS on both sides.
+// Check for a separator, optionally with LWS on both sides. LWS that
+// ends the line without folding ends the record: the next line must
+// not be mistaken for the separator or value of this one.
 func lexSep(l *lexer) lexfn {
-	skipLWS(l)
+	if !skipLWS(l) {
+		l.error(MissingSeparator)
+		return lexStart
+	}
 	if c := l.next(); c != ':' {
-		l.errorf("expected separator betweeen field and value")
+		l.error(MissingSeparator)
 		return lexNextLine
 	}
-	skipLWS(l)
+	// An empty value is positioned immediately after the
+	// separator.
+	l.valuePos = l.pos
+	if !skipLWS(l) {
+		// The value is empty.
+		l.emit()
+		return lexStart
+	}
+	l.valuePos = l.pos
 	return lexValue
 }
 
@@ -217,7 +283,7 @@ func lexEOL(l *lexer) lexfn {
 		l.ignore()
 		return lexStart
 	}
-	l.errorf("expected EOL")
+	l.error(UnexpectedText)
 	return lexNextLine
 }
 
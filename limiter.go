@@ -0,0 +1,141 @@
+package robots
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxDelay is the longest delay a Limiter waits between
+// requests to a host, unless its Max says otherwise. Some robots.txt
+// files declare delays of hours or days, which would stall a crawler.
+const DefaultMaxDelay = time.Minute
+
+// minSweep is the number of hosts a Limiter tracks before it first
+// forgets those it no longer delays.
+const minSweep = 64
+
+// A Clock tells the time and waits. It allows a Limiter to be tested
+// without waiting in real time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is a Clock using the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// A Limiter spaces out requests to each host according to the crawl
+// delay its robots.txt file declares for Agent; see
+// Robots.CrawlDelay. Hosts are distinguished the same way as
+// robots.txt files: by the URL returned by Locate.
+//
+// The zero value of a Limiter is not ready to use: it needs a Cache.
+// A Limiter is safe for concurrent use by multiple goroutines.
+type Limiter struct {
+	// Cache provides robots.txt files. It must not be nil.
+	Cache *Cache
+
+	// Agent is the name whose crawl delay is used.
+	Agent string
+
+	// Default is the delay between requests to hosts whose
+	// robots.txt file declares none for Agent.
+	Default time.Duration
+
+	// Max is the longest delay between requests. Longer delays
+	// are clamped to it. If zero, DefaultMaxDelay is used.
+	Max time.Duration
+
+	// Clock is used to tell the time and wait. If nil, the time
+	// package is used.
+	Clock Clock
+
+	mu      sync.Mutex
+	next    map[string]time.Time // when each host may next be requested
+	sweepAt int                  // size of next at which to sweep it
+}
+
+// Wait blocks until a request for rawurl is allowed, and reserves that
+// request: a later call for the same host waits for the delay after
+// it. It returns an error if rawurl is invalid or ctx is done first. A
+// request reserved by a call that returns an error still delays later
+// calls.
+//
+// Wait does not test whether robots.txt allows rawurl; use
+// Cache.Allowed for that.
+func (l *Limiter) Wait(ctx context.Context, rawurl string) error {
+	res, err := l.Cache.Fetch(ctx, rawurl)
+	if err != nil {
+		return err
+	}
+	delay := l.delay(res.Robots)
+	clock := l.clock()
+
+	l.mu.Lock()
+	now := clock.Now()
+	if len(l.next) >= l.sweepAt {
+		l.sweep(now)
+	}
+	t := l.next[res.URL]
+	if t.Before(now) {
+		t = now
+	}
+	if l.next == nil {
+		l.next = make(map[string]time.Time)
+	}
+	l.next[res.URL] = t.Add(delay)
+	l.mu.Unlock()
+
+	if t.Equal(now) {
+		return nil
+	}
+	select {
+	case <-clock.After(t.Sub(now)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sweep removes the hosts that may be requested at once, which need no
+// entry in l.next. It runs each time l.next doubles in size, so that
+// l.next stays in proportion to the number of hosts being delayed,
+// at a constant cost per call of Wait. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	for host, t := range l.next {
+		if !t.After(now) {
+			delete(l.next, host)
+		}
+	}
+	l.sweepAt = 2 * len(l.next)
+	if l.sweepAt < minSweep {
+		l.sweepAt = minSweep
+	}
+}
+
+// delay returns the delay between requests to the host of r.
+func (l *Limiter) delay(r *Robots) time.Duration {
+	d, ok := r.CrawlDelay(l.Agent)
+	if !ok {
+		d = l.Default
+	}
+	max := l.Max
+	if max <= 0 {
+		max = DefaultMaxDelay
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func (l *Limiter) clock() Clock {
+	if l.Clock != nil {
+		return l.Clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,132 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: *\ncrawl-delay: 5\n\nuser-agent: ratebot\nrequest-rate: 1/2\n\nuser-agent: slowbot\ncrawl-delay: 86400\n")
+	}))
+	defer ts.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer other.Close()
+
+	var tests = []struct {
+		agent string
+		urls  []string
+		waits []time.Duration
+	}{
+		{"Crawlerbot", []string{ts.URL + "/a", ts.URL + "/b", ts.URL + "/c"}, []time.Duration{5 * time.Second, 5 * time.Second}},
+		{"ratebot", []string{ts.URL + "/a", ts.URL + "/b"}, []time.Duration{2 * time.Second}},
+		{"slowbot", []string{ts.URL + "/a", ts.URL + "/b"}, []time.Duration{time.Minute}},
+		// A host without a crawl delay uses the default.
+		{"Crawlerbot", []string{other.URL + "/a", other.URL + "/b"}, []time.Duration{time.Second}},
+		// Hosts are limited independently.
+		{"Crawlerbot", []string{ts.URL + "/a", other.URL + "/a", ts.URL + "/b"}, []time.Duration{5 * time.Second}},
+	}
+
+	for i, test := range tests {
+		clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+		l := &Limiter{
+			Cache:   &Cache{},
+			Agent:   test.agent,
+			Default: time.Second,
+			Clock:   clock,
+		}
+		for _, u := range test.urls {
+			if err := l.Wait(context.Background(), u); err != nil {
+				t.Fatalf("test %d: Wait returned error: %v", i, err)
+			}
+		}
+		if fmt.Sprint(clock.waits) != fmt.Sprint(test.waits) {
+			t.Errorf("test %d: waited %v, want %v", i, clock.waits, test.waits)
+		}
+	}
+}
+
+func TestLimiterElapsed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: *\ncrawl-delay: 10\n")
+	}))
+	defer ts.Close()
+
+	clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := &Limiter{Cache: &Cache{}, Clock: clock}
+	ctx := context.Background()
+	l.Wait(ctx, ts.URL)
+	clock.advance(4 * time.Second)
+	l.Wait(ctx, ts.URL)
+	clock.advance(20 * time.Second)
+	l.Wait(ctx, ts.URL)
+	if want := []time.Duration{6 * time.Second}; fmt.Sprint(clock.waits) != fmt.Sprint(want) {
+		t.Errorf("waited %v, want %v", clock.waits, want)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: *\ncrawl-delay: 10\n")
+	}))
+	defer ts.Close()
+
+	l := &Limiter{Cache: &Cache{}}
+	if err := l.Wait(context.Background(), ts.URL); err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, ts.URL); err != context.DeadlineExceeded {
+		t.Errorf("Wait returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// notFoundTransport answers every request with a 404 status code.
+type notFoundTransport struct{}
+
+func (notFoundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+// TestLimiterForgetsHosts checks that a Limiter does not keep an entry
+// for every host it has seen.
+func TestLimiterForgetsHosts(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := &Limiter{
+		Cache:   &Cache{Fetcher: &Fetcher{Client: &http.Client{Transport: notFoundTransport{}}}},
+		Default: time.Second,
+		Clock:   clock,
+	}
+	const n = 1000
+	for round := 0; round < 2; round++ {
+		for i := 0; i < n; i++ {
+			u := fmt.Sprintf("http://host%d-%d.example.com/", round, i)
+			if err := l.Wait(context.Background(), u); err != nil {
+				t.Fatalf("Wait returned error: %v", err)
+			}
+		}
+		clock.advance(2 * time.Second)
+	}
+	for i := 0; i < n; i++ {
+		if _, ok := l.next[fmt.Sprintf("http://host0-%d.example.com/robots.txt", i)]; ok {
+			t.Fatalf("Limiter still tracks a host it no longer delays")
+		}
+	}
+	if len(l.next) > n {
+		t.Errorf("Limiter tracks %d hosts, want at most %d", len(l.next), n)
+	}
+}
@@ -0,0 +1,123 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// A lineReader reads a robots.txt file a line at a time, so that no
+// more of the file is held in memory than the parser keeps, and stops
+// at the end of the last line within a byte limit.
+//
+// Lines are read up to each "\n", but the lexer also ends a record at
+// a bare "\r", as in files with old Mac line endings. Such a file is a
+// single line here, so a line that crosses the limit is cut after the
+// last "\r" within it, rather than dropped whole.
+//
+// The lexer is run over chunks of lines rather than single lines,
+// because LWS can fold a record onto the next line. A chunk ends
+// before a line that begins with anything other than a space, a tab
+// or a line feed, since the lexer is always at the start of a record
+// there.
+type lineReader struct {
+	r         *bufio.Reader // nil if reading from s
+	s         string        // the unread input, if r is nil
+	limit     int64         // bytes that may still be read, or negative
+	next      string        // a line read ahead, or ""
+	line      int           // line number of next, starting at 1
+	bom       bool          // whether the BOM has been checked for
+	truncated bool          // whether a line was cut by the limit
+}
+
+func newLineReader(r io.Reader, limit int64) *lineReader {
+	if limit >= 0 {
+		// The one byte past the limit shows whether there is more
+		// input, without reading a whole line beyond it.
+		r = io.LimitReader(r, limit+1)
+	}
+	return &lineReader{r: bufio.NewReader(r), limit: limit, line: 1}
+}
+
+func newStringLineReader(s string) *lineReader {
+	return &lineReader{s: s, limit: -1, line: 1}
+}
+
+// readLine returns the next line of input, including its line ending,
+// or "" at the end of the input or the limit.
+func (lr *lineReader) readLine() (string, error) {
+	var line string
+	var err error
+	if lr.r == nil {
+		end := strings.IndexByte(lr.s, '\n') + 1
+		if end == 0 {
+			end = len(lr.s)
+		}
+		line, lr.s = lr.s[:end], lr.s[end:]
+	} else {
+		line, err = lr.r.ReadString('\n')
+		if err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if lr.limit >= 0 {
+		if int64(len(line)) > lr.limit {
+			lr.truncated = true
+			line = line[:strings.LastIndexByte(line[:lr.limit], '\r')+1]
+			lr.limit = 0
+		} else {
+			lr.limit -= int64(len(line))
+		}
+	}
+	if !lr.bom {
+		lr.bom = true
+		line = stripBOM(line)
+	}
+	return line, nil
+}
+
+// chunk returns the next chunk of input and the line number it begins
+// on. It returns "" at the end of the input or the limit.
+func (lr *lineReader) chunk() (string, int, error) {
+	first, line := lr.next, lr.line
+	if first == "" {
+		var err error
+		if first, err = lr.readLine(); err != nil {
+			return "", 0, err
+		}
+	}
+	lr.next = ""
+	chunk := first
+	var b strings.Builder
+	for {
+		next, err := lr.readLine()
+		if err != nil {
+			return "", 0, err
+		}
+		if next == "" || !continues(next) {
+			lr.next = next
+			break
+		}
+		if b.Len() == 0 {
+			b.WriteString(first)
+		}
+		b.WriteString(next)
+	}
+	if b.Len() > 0 {
+		chunk = b.String()
+	}
+	lr.line = line + strings.Count(chunk, "\n")
+	return chunk, line, nil
+}
+
+// continues reports whether a line may continue the chunk before it.
+func continues(line string) bool {
+	switch line[0] {
+	case ' ', '\t', '\n':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package robots
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineReaderChunks(t *testing.T) {
+	var tests = []struct {
+		in     string
+		chunks []string
+		lines  []int
+	}{
+		{"", nil, nil},
+		{"a\nb", []string{"a\n", "b"}, []int{1, 2}},
+		{"\ufeffa\n", []string{"a\n"}, []int{1}},
+		{"a\n  b\n\tc\nd\n", []string{"a\n  b\n\tc\n", "d\n"}, []int{1, 4}},
+		{"a\n\n\n b\n", []string{"a\n\n\n b\n"}, []int{1}},
+		{"a\r\n\r\n b\r\n", []string{"a\r\n", "\r\n b\r\n"}, []int{1, 2}},
+		{" a\nb\n", []string{" a\n", "b\n"}, []int{1, 2}},
+	}
+
+	for _, test := range tests {
+		for _, lr := range []*lineReader{
+			newStringLineReader(stripBOM(test.in)),
+			newLineReader(iotest.OneByteReader(strings.NewReader(test.in)), -1),
+		} {
+			var chunks []string
+			var lines []int
+			for {
+				chunk, line, err := lr.chunk()
+				if err != nil {
+					t.Fatalf("chunk of %q returned error: %v", test.in, err)
+				}
+				if chunk == "" {
+					break
+				}
+				chunks = append(chunks, chunk)
+				lines = append(lines, line)
+			}
+			if !reflect.DeepEqual(chunks, test.chunks) || !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("chunks of %q = %q at %v, want %q at %v",
+					test.in, chunks, lines, test.chunks, test.lines)
+			}
+		}
+	}
+}
+
+// TestParseReader checks that parsing a file in chunks gives the same
+// result, and the same diagnostics, as lexing it whole.
+func TestParseReader(t *testing.T) {
+	fnames, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fname := range fnames {
+		buf, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := stripBOM(string(buf))
+		whole := &parser{
+			lines:      newStringLineReader(""),
+			input:      input,
+			lexer:      lex(input),
+			robotsdata: &robotsdata{},
+			diagnose:   true,
+		}
+		want, _ := whole.run()
+
+		r, diagnostics, err := ParseWithDiagnostics(iotest.HalfReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatalf("%s: ParseWithDiagnostics returned error: %v", fname, err)
+		}
+		if !reflect.DeepEqual(r.groups, want.groups) || !reflect.DeepEqual(r.sitemaps, want.sitemaps) {
+			t.Errorf("%s: parsing in chunks gave groups %+v, want %+v", fname, r.Groups(), want.groups)
+		}
+		if !reflect.DeepEqual(diagnostics, whole.diagnostics) {
+			t.Errorf("%s: parsing in chunks gave diagnostics %v, want %v", fname, diagnostics, whole.diagnostics)
+		}
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	const file = "user-agent: *\ndisallow: /a\ndisallow: /b\n"
+	var tests = []struct {
+		limit     int64
+		blocked   []string
+		truncated bool
+	}{
+		{0, []string{"/a", "/b"}, false},
+		{int64(len(file)), []string{"/a", "/b"}, false},
+		{int64(len(file)) - 1, []string{"/a"}, true},
+		{int64(len("user-agent: *\ndisallow: /a\n")), []string{"/a"}, true},
+		{int64(len("user-agent: *\ndisallow: /")), nil, true},
+		{1, nil, true},
+		{-1, []string{"/a", "/b"}, false},
+	}
+
+	for _, test := range tests {
+		r, err := From(200, strings.NewReader(file), SizeLimit(test.limit))
+		if err != nil {
+			t.Fatalf("From returned error: %v", err)
+		}
+		for _, path := range []string{"/a", "/b"} {
+			blocked := false
+			for _, p := range test.blocked {
+				blocked = blocked || p == path
+			}
+			if r.Test("crawlerbot", path) == blocked {
+				t.Errorf("limit %d: Test(%q) = %t, want %t", test.limit, path, !blocked, !blocked)
+			}
+		}
+		if r.Truncated() != test.truncated {
+			t.Errorf("limit %d: Truncated() = %t, want %t", test.limit, r.Truncated(), test.truncated)
+		}
+	}
+
+	// A BOM counts toward the limit.
+	r, _ := From(200, strings.NewReader("\ufeff"+file), SizeLimit(int64(len(file))))
+	if !r.Truncated() || r.Test("crawlerbot", "/a") || !r.Test("crawlerbot", "/b") {
+		t.Errorf("BOM not counted toward the limit")
+	}
+
+	data, _ := r.MarshalBinary()
+	again := &Robots{}
+	if err := again.UnmarshalBinary(data); err != nil || !again.Truncated() {
+		t.Errorf("Truncated not kept by MarshalBinary")
+	}
+}
+
+func TestSizeLimitDefault(t *testing.T) {
+	pad := strings.Repeat("#", 99) + "\n"
+	file := "user-agent: *\n" + strings.Repeat(pad, defaultSizeLimit/len(pad)) + "disallow: /\n"
+	r, _ := From(200, strings.NewReader(file))
+	if !r.Truncated() || !r.Test("crawlerbot", "/") {
+		t.Errorf("rule after %d bytes was not ignored", defaultSizeLimit)
+	}
+}
+
+// TestSizeLimitCR checks that a file with old Mac line endings, which
+// is one long line to the lineReader, is cut at the last record within
+// the limit.
+func TestSizeLimitCR(t *testing.T) {
+	for _, eol := range []string{"\n", "\r", "\r\n"} {
+		file := "user-agent: *" + eol + "disallow: /private" + eol + strings.Repeat("allow: /x"+eol, 600)
+		r, _ := From(200, strings.NewReader(file), SizeLimit(5000))
+		if !r.Truncated() || r.Test("crawlerbot", "/private") || !r.Test("crawlerbot", "/x") {
+			t.Errorf("%q endings: rules within the limit were not kept", eol)
+		}
+	}
+
+	const file = "user-agent: *\rdisallow: /a\rdisallow: /b\r"
+	r, _ := From(200, strings.NewReader(file), SizeLimit(int64(len(file))-1))
+	if !r.Truncated() || r.Test("crawlerbot", "/a") || !r.Test("crawlerbot", "/b") {
+		t.Errorf("record cut by the limit was not dropped")
+	}
+}
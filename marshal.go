@@ -0,0 +1,218 @@
+package robots
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// binaryVersion is the version of the format written by MarshalBinary.
+// It must change whenever the format changes in a way older code
+// cannot read, so that stored data in the old format is rejected
+// rather than misread.
+const binaryVersion = 2
+
+// robotsJSON is the serialized form of a Robots object.
+type robotsJSON struct {
+	Version   int         `json:"v"`
+	Allow     bool        `json:"allow"`
+	Status    Status      `json:"status"`
+	Config    configJSON  `json:"config"`
+	Groups    []groupJSON `json:"groups,omitempty"`
+	Sitemaps  []string    `json:"sitemaps,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+type configJSON struct {
+	RFC9309        bool `json:"rfc9309,omitempty"`
+	PrefixAgents   bool `json:"prefixAgents,omitempty"`
+	FullUserAgents bool `json:"fullUserAgents,omitempty"`
+}
+
+type groupJSON struct {
+	Agents      []string      `json:"agents"`
+	Rules       []ruleJSON    `json:"rules,omitempty"`
+	CrawlDelay  time.Duration `json:"crawlDelay,omitempty"`
+	HasDelay    bool          `json:"hasCrawlDelay,omitempty"`
+	RequestRate string        `json:"requestRate,omitempty"`
+}
+
+type ruleJSON struct {
+	Allow   bool   `json:"allow,omitempty"`
+	Pattern string `json:"pattern"`
+	Line    int    `json:"line"`
+}
+
+// resultJSON is the serialized form of a Result.
+type resultJSON struct {
+	Version      int        `json:"v"`
+	Robots       robotsJSON `json:"robots"`
+	URL          string     `json:"url"`
+	FinalURL     string     `json:"finalURL,omitempty"`
+	StatusCode   int        `json:"statusCode,omitempty"`
+	Fetched      time.Time  `json:"fetched"`
+	Expires      time.Time  `json:"expires"`
+	ETag         string     `json:"etag,omitempty"`
+	LastModified string     `json:"lastModified,omitempty"`
+	Err          string     `json:"err,omitempty"`
+}
+
+// MarshalBinary encodes r in a compact, versioned form that
+// UnmarshalBinary decodes into an equivalent Robots object. Unlike
+// MarshalText, it keeps the default allow state, the Status, the
+// options r was parsed with, and the line numbers of rules. Comments
+// and invalid lines are not kept.
+func (r *Robots) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r.toJSON())
+}
+
+// UnmarshalBinary decodes data written by MarshalBinary and sets r to
+// the result. It returns an error if data was written in a different
+// version of the format.
+func (r *Robots) UnmarshalBinary(data []byte) error {
+	var v robotsJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return r.fromJSON(v)
+}
+
+func (r *Robots) toJSON() robotsJSON {
+	v := robotsJSON{
+		Version:   binaryVersion,
+		Allow:     r.allow,
+		Status:    r.status,
+		Sitemaps:  r.sitemaps,
+		Truncated: r.truncated,
+		Config: configJSON{
+			RFC9309:        r.config.rfc9309,
+			PrefixAgents:   r.config.prefixAgents,
+			FullUserAgents: r.config.fullUserAgents,
+		},
+	}
+	for _, g := range r.groups {
+		gv := groupJSON{
+			Agents:      g.Agents,
+			CrawlDelay:  g.CrawlDelay,
+			HasDelay:    g.HasCrawlDelay,
+			RequestRate: g.RequestRate,
+		}
+		for _, rule := range g.Rules {
+			gv.Rules = append(gv.Rules, ruleJSON(rule))
+		}
+		v.Groups = append(v.Groups, gv)
+	}
+	return v
+}
+
+func (r *Robots) fromJSON(v robotsJSON) error {
+	if v.Version != binaryVersion {
+		return fmt.Errorf("unsupported format version: %d", v.Version)
+	}
+	c := config{
+		rfc9309:        v.Config.RFC9309,
+		prefixAgents:   v.Config.PrefixAgents,
+		fullUserAgents: v.Config.FullUserAgents,
+	}
+	groups := make([]*Group, len(v.Groups))
+	for i, gv := range v.Groups {
+		g := &Group{
+			Agents:        gv.Agents,
+			CrawlDelay:    gv.CrawlDelay,
+			HasCrawlDelay: gv.HasDelay,
+			RequestRate:   gv.RequestRate,
+		}
+		for _, rule := range gv.Rules {
+			g.Rules = append(g.Rules, Rule(rule))
+		}
+		groups[i] = g
+	}
+	data := fromGroups(groups, v.Sitemaps, c)
+	data.truncated = v.Truncated
+	*r = Robots{
+		allow:      v.Allow,
+		status:     v.Status,
+		robotsdata: data,
+	}
+	return nil
+}
+
+// fromGroups builds the data of a Robots object from its groups, as
+// the parser would have from a file containing them.
+func fromGroups(groups []*Group, sitemaps []string, c config) *robotsdata {
+	data := &robotsdata{config: c}
+	for _, g := range groups {
+		agents := make([]*agent, len(g.Agents))
+		for i, name := range g.Agents {
+			agents[i] = &agent{name: name}
+			for _, rule := range g.Rules {
+				agents[i].group.addMember(&member{
+					allow: rule.Allow,
+					path:  rule.Pattern,
+					line:  rule.Line,
+				}, c)
+			}
+			if d, ok := g.delay(); ok {
+				agents[i].group.setDelay(d)
+			}
+		}
+		data.addAgents(agents)
+		data.groups = append(data.groups, g)
+	}
+	data.sitemaps = sitemaps
+	data.buildIndex()
+	return data
+}
+
+// MarshalBinary encodes res, including its Robots object, in the form
+// described by Robots.MarshalBinary. The header of the response is not
+// kept, apart from its validators, and Err is kept only as text.
+func (res *Result) MarshalBinary() ([]byte, error) {
+	v := resultJSON{
+		Version:      binaryVersion,
+		Robots:       res.Robots.toJSON(),
+		URL:          res.URL,
+		FinalURL:     res.FinalURL,
+		StatusCode:   res.StatusCode,
+		Fetched:      res.Fetched,
+		Expires:      res.Expires,
+		ETag:         res.ETag,
+		LastModified: res.LastModified,
+	}
+	if res.Err != nil {
+		v.Err = res.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalBinary decodes data written by Result.MarshalBinary and
+// sets res to the result. It returns an error if data was written in a
+// different version of the format.
+func (res *Result) UnmarshalBinary(data []byte) error {
+	var v resultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version != binaryVersion {
+		return fmt.Errorf("unsupported format version: %d", v.Version)
+	}
+	r := &Robots{}
+	if err := r.fromJSON(v.Robots); err != nil {
+		return err
+	}
+	*res = Result{
+		Robots:       r,
+		URL:          v.URL,
+		FinalURL:     v.FinalURL,
+		StatusCode:   v.StatusCode,
+		Fetched:      v.Fetched,
+		Expires:      v.Expires,
+		ETag:         v.ETag,
+		LastModified: v.LastModified,
+	}
+	if v.Err != "" {
+		res.Err = errors.New(v.Err)
+	}
+	return nil
+}
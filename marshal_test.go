@@ -0,0 +1,122 @@
+package robots
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalBinaryRoundTrip(t *testing.T) {
+	fnames, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agents := []string{"a", "b", "e", "googlebot", "Googlebot-News", "bingbot", "crawler", "crawlerbot"}
+	paths := []string{"/", "/c", "/d", "/g", "/page", "/folder/page", "/x.htm",
+		"/exact-match", "/images", "/images/my-cool-image.png", "/app.js", "/robots.txt"}
+	options := [][]Option{nil, {RFC9309()}, {PrefixAgentMatching()}}
+
+	for _, fname := range fnames {
+		buf, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range []int{200, 404, 503} {
+			for _, opts := range options {
+				r, _ := From(status, bytes.NewReader(buf), opts...)
+				data, err := r.MarshalBinary()
+				if err != nil {
+					t.Fatalf("%s: MarshalBinary returned error: %v", fname, err)
+				}
+				again := &Robots{}
+				if err := again.UnmarshalBinary(data); err != nil {
+					t.Fatalf("%s: UnmarshalBinary returned error: %v", fname, err)
+				}
+
+				if !reflect.DeepEqual(again.Groups(), r.Groups()) {
+					t.Errorf("%s: groups after round trip = %+v, want %+v", fname, again.Groups(), r.Groups())
+				}
+				if !reflect.DeepEqual(again.Sitemaps(), r.Sitemaps()) {
+					t.Errorf("%s: sitemaps after round trip = %v, want %v", fname, again.Sitemaps(), r.Sitemaps())
+				}
+				if again.Status() != r.Status() || again.config != r.config {
+					t.Errorf("%s: status %v, config %+v after round trip, want %v, %+v",
+						fname, again.Status(), again.config, r.Status(), r.config)
+				}
+				for _, agent := range agents {
+					for _, path := range paths {
+						if got, want := again.Explain(agent, path), r.Explain(agent, path); got != want {
+							t.Errorf("%s: Explain(%q, %q) after round trip = %+v, want %+v",
+								fname, agent, path, got, want)
+						}
+					}
+					gotDelay, gotOK := again.CrawlDelay(agent)
+					wantDelay, wantOK := r.CrawlDelay(agent)
+					if gotDelay != wantDelay || gotOK != wantOK {
+						t.Errorf("%s: CrawlDelay(%q) after round trip = %v, %t, want %v, %t",
+							fname, agent, gotDelay, gotOK, wantDelay, wantOK)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMarshalBinaryVersion(t *testing.T) {
+	r := &Robots{}
+	for _, data := range []string{
+		`{"v":1,"allow":true}`,
+		`{"v":3,"allow":true}`,
+		`not json`,
+	} {
+		if err := r.UnmarshalBinary([]byte(data)); err == nil {
+			t.Errorf("UnmarshalBinary(%s) should fail", data)
+		}
+	}
+}
+
+func TestResultMarshalBinary(t *testing.T) {
+	r, _ := From(200, bytes.NewReader([]byte("user-agent: *\ndisallow: /private\n")))
+	res := &Result{
+		Robots:       r,
+		URL:          "https://example.com/robots.txt",
+		FinalURL:     "https://www.example.com/robots.txt",
+		StatusCode:   200,
+		Fetched:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Expires:      time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		ETag:         `"v1"`,
+		LastModified: "Wed, 01 Jan 2020 00:00:00 GMT",
+	}
+	data, err := res.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %v", err)
+	}
+	got := &Result{}
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if got.Test("Crawlerbot", "/private") || !got.Test("Crawlerbot", "/public") {
+		t.Errorf("rules not kept")
+	}
+	got.Robots, res.Robots = nil, nil
+	if !reflect.DeepEqual(got, res) {
+		t.Errorf("result after round trip = %+v, want %+v", got, res)
+	}
+
+	errTest := errors.New("connection refused")
+	failed := &Result{URL: "https://example.com/robots.txt"}
+	failed.fail(errTest, nil)
+	data, _ = failed.MarshalBinary()
+	got = &Result{}
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %v", err)
+	}
+	if got.Err == nil || got.Err.Error() != errTest.Error() || got.Status() != Unreachable {
+		t.Errorf("failed result after round trip: err %v, status %v", got.Err, got.Status())
+	}
+}
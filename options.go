@@ -0,0 +1,164 @@
+package robots
+
+import (
+	"strings"
+)
+
+// An Option changes how a robots.txt file is interpreted. Options are
+// passed to From and ParseWithDiagnostics.
+type Option func(*config)
+
+type config struct {
+	rfc9309        bool
+	prefixAgents   bool
+	fullUserAgents bool
+	sizeLimit      int64 // 0 means defaultSizeLimit, negative no limit
+}
+
+// defaultSizeLimit is the number of bytes of a robots.txt file that
+// are read unless the SizeLimit option says otherwise. Google's
+// implementation also stops at 500 KiB.
+const defaultSizeLimit = 500 << 10
+
+func makeConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// RFC9309 makes a Robots object follow RFC 9309 where it differs from
+// Google's earlier specification, which this package follows by
+// default:
+//
+// When an allow rule and a disallow rule match a path with patterns
+// of the same length, the allow rule wins. By default, the rule that
+// occurs later in the file wins.
+//
+// Characters outside US-ASCII, in both patterns and paths, are
+// compared in percent-encoded form. Percent-encoded unreserved
+// characters (letters, digits, "-", ".", "_" and "~") are decoded
+// before comparison, so "/%62%61%7A" and "/baz" are the same; other
+// percent-encoded characters are not.
+//
+// The URL /robots.txt is always allowed.
+//
+// See https://www.rfc-editor.org/rfc/rfc9309.
+func RFC9309() Option {
+	return func(c *config) {
+		c.rfc9309 = true
+	}
+}
+
+// productToken returns the product token that begins name, in
+// lowercase. If name begins with "*", the token is "*".
+func productToken(name string) string {
+	if strings.HasPrefix(name, "*") {
+		return "*"
+	}
+	end := strings.IndexFunc(name, func(c rune) bool {
+		return !isTokenChar(c)
+	})
+	if end < 0 {
+		end = len(name)
+	}
+	return strings.ToLower(name[:end])
+}
+
+// percentEncode returns s with every byte outside US-ASCII
+// percent-encoded, existing percent-encodings of unreserved characters
+// decoded, and the hexadecimal digits of other percent-encodings in
+// uppercase, so that equivalent encodings compare equal. This is the
+// comparison RFC 9309 requires in section 2.2.2.
+func percentEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 0x80:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			if d := unhex(s[i+1])<<4 | unhex(s[i+2]); isUnreserved(d) {
+				b.WriteByte(d)
+			} else {
+				b.WriteByte('%')
+				b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			}
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+// isUnreserved reports whether c is an unreserved character, as
+// defined in RFC 3986.
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// PrefixAgentMatching restores the way this package used to match
+// agents. By default, agents are matched by product token: the
+// leading letters, hyphens and underscores of a name, compared
+// without regard to case. So "Googlebot/2.1" matches a group for
+// "googlebot", but "Googlebot-Image" does not, and uses the group for
+// "*" instead. With PrefixAgentMatching, a group matches any name it
+// is a prefix of, without regard to case, and the group with the
+// longest name wins. No specification defines this behavior.
+func PrefixAgentMatching() Option {
+	return func(c *config) {
+		c.prefixAgents = true
+	}
+}
+
+// FullUserAgents makes Test, Tester, Explain and CrawlDelay accept the
+// full value of a User-Agent header as the name of an agent, like
+// "Mozilla/5.0 (compatible; Crawlerbot/2.1; +https://example.com/bot)".
+// The name of the crawler is extracted with ProductToken.
+func FullUserAgents() Option {
+	return func(c *config) {
+		c.fullUserAgents = true
+	}
+}
+
+// SizeLimit sets the number of bytes of a robots.txt file that are
+// read to n, instead of 500 KiB. If n is zero or negative, the whole
+// file is read. The file is cut at the end of the last line that fits
+// within the limit, and the rest is ignored; see Robots.Truncated.
+func SizeLimit(n int64) Option {
+	return func(c *config) {
+		c.sizeLimit = n
+		if n <= 0 {
+			c.sizeLimit = -1
+		}
+	}
+}
+
+// limit returns the number of bytes of a file to read, or a negative
+// number if there is no limit.
+func (c config) limit() int64 {
+	if c.sizeLimit == 0 {
+		return defaultSizeLimit
+	}
+	return c.sizeLimit
+}
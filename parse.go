@@ -1,28 +1,94 @@
 package robots
 
-import "strings"
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type parser struct {
 	agents      []*agent
+	group       *Group // The current group, as it occurs in the source.
 	withinGroup bool
-	items       []*item
+	lines       *lineReader
+	input       string // the chunk of input being lexed
+	lexer       *lexer
+	item        item // the item being parsed
+	err         error
 	robotsdata  *robotsdata
+	// If diagnose is true, discarded lines are recorded in
+	// diagnostics.
+	diagnose    bool
+	diagnostics []Diagnostic
 }
 
 type parsefn func(p *parser) parsefn
 
-func parse(s string) *robotsdata {
-	p := &parser{
-		items:      lex(s),
-		robotsdata: &robotsdata{},
+// parse parses s, which is held in memory already, so is not subject
+// to a size limit.
+func parse(s string, c config) *robotsdata {
+	data, _ := newParser(newStringLineReader(s), c).run()
+	return data
+}
+
+// parseReader parses the file read from r, up to the size limit of c.
+func parseReader(r io.Reader, c config) (*robotsdata, error) {
+	return newParser(newLineReader(r, c.limit()), c).run()
+}
+
+func newParser(lines *lineReader, c config) *parser {
+	return &parser{
+		lines:      lines,
+		lexer:      lex(""),
+		robotsdata: &robotsdata{config: c},
+	}
+}
+
+func (p *parser) run() (*robotsdata, error) {
+	if p.nextItem() {
+		for fn := parseStart; fn != nil; fn = fn(p) {
+		}
 	}
-	for fn := parseStart; fn != nil; fn = fn(p) {
+	p.robotsdata.truncated = p.lines.truncated
+	return p.robotsdata, p.err
+}
+
+// nextItem sets p.item to the next item of the input, lexing the next
+// chunk of lines when the current one is used up. It reports false at
+// the end of the input, or if reading it failed.
+func (p *parser) nextItem() bool {
+	for {
+		var ok bool
+		if p.item, ok = p.lexer.nextItem(); ok {
+			return true
+		}
+		chunk, line, err := p.lines.chunk()
+		if err != nil {
+			p.err = err
+			return false
+		}
+		if chunk == "" {
+			return false
+		}
+		p.input = chunk
+		p.lexer.reset(chunk, line)
+	}
+}
+
+// discard records that the current item was discarded for the given
+// reason, if p is collecting diagnostics.
+func (p *parser) discard(reason Reason) {
+	if !p.diagnose {
+		return
 	}
-	return p.robotsdata
+	p.diagnostics = append(p.diagnostics,
+		diagnosticAt(p.input, p.item.pos, p.item.line, reason))
 }
 
 func parseStart(p *parser) parsefn {
-	switch p.items[0].typ {
+	switch p.item.typ {
 	case itemUserAgent:
 		return parseUserAgent
 	case itemDisallow:
@@ -31,7 +97,12 @@ func parseStart(p *parser) parsefn {
 		return parseAllow
 	case itemSitemap:
 		return parseSitemap
+	case itemCrawlDelay:
+		return parseCrawlDelay
+	case itemRequestRate:
+		return parseRequestRate
 	default:
+		p.discard(p.item.reason)
 		return parseNext
 	}
 }
@@ -42,23 +113,51 @@ func parseStart(p *parser) parsefn {
 // rule was also a user-agent rule and we're associating another agent
 // with the forthcoming group) then we add another agent to p.agents.
 func parseUserAgent(p *parser) parsefn {
+	if p.item.val == "" {
+		// An empty user-agent line names no agent, so it is
+		// discarded. It still ends the group before it: the
+		// rules that follow it apply to no agent, unless other
+		// user-agent lines name one.
+		p.discard(EmptyAgent)
+		if p.withinGroup {
+			p.robotsdata.addAgents(p.agents)
+			p.agents = nil
+			p.group = nil
+			p.withinGroup = false
+		}
+		return parseNext
+	}
 	if p.withinGroup { // The previous rule was allow or disallow
 		p.robotsdata.addAgents(p.agents)
 		p.agents = []*agent{
 			&agent{
-				name: p.items[0].val,
+				name: p.item.val,
 			},
 		}
 		p.withinGroup = false // Now we're before the start of a group
+		p.newGroup()
+		p.group.Agents = append(p.group.Agents, p.item.val)
 		return parseNext
 	}
 	// The previous rule was another user-agent rule
+	if len(p.agents) == 0 {
+		p.newGroup()
+	}
 	p.agents = append(p.agents, &agent{
-		name: p.items[0].val,
+		name: p.item.val,
 	})
+	p.group.Agents = append(p.group.Agents, p.item.val)
 	return parseNext
 }
 
+// newGroup begins recording a new group of rules as it occurs in the
+// source. This record is only used to report the structure of the
+// file, not for matching.
+func (p *parser) newGroup() {
+	p.group = &Group{}
+	p.robotsdata.groups = append(p.robotsdata.groups, p.group)
+}
+
 // parseAllow and parseDisallow are identical except for what they set
 // the allow field of the member to. Therefore, we have this factory
 // function.
@@ -69,8 +168,11 @@ func makeParseMember(allow bool) func(*parser) parsefn {
 		// any user-agent rules. That's fine, it results in
 		// the desired behavior.
 		p.withinGroup = true
+		if len(p.agents) == 0 {
+			p.discard(OrphanRule)
+		}
 		// If there is no path, do nothing.
-		if strings.TrimSpace(p.items[0].val) == "" {
+		if strings.TrimSpace(p.item.val) == "" {
 			return parseNext
 		}
 		// If there is no agent (i.e., the rules come before
@@ -79,9 +181,17 @@ func makeParseMember(allow bool) func(*parser) parsefn {
 		for _, agent := range p.agents {
 			m := &member{
 				allow: allow,
-				path:  p.items[0].val,
+				path:  p.item.val,
+				line:  p.item.line,
 			}
-			agent.group.addMember(m)
+			agent.group.addMember(m, p.robotsdata.config)
+		}
+		if p.group != nil {
+			p.group.Rules = append(p.group.Rules, Rule{
+				Allow:   allow,
+				Pattern: p.item.val,
+				Line:    p.item.line,
+			})
 		}
 		return parseNext
 	}
@@ -91,23 +201,136 @@ var parseDisallow parsefn
 
 var parseAllow parsefn
 
+// parseCrawlDelay records the delay of a crawl-delay rule, and
+// parseRequestRate the delay between requests implied by a
+// request-rate rule.
+var parseCrawlDelay parsefn
+
+var parseRequestRate parsefn
+
 func init() {
 	// These variables must be initiated at run-time to avoid a
 	// definition loop.
 	parseDisallow = makeParseMember(false)
 	parseAllow = makeParseMember(true)
+	parseCrawlDelay = makeParseDelay(parseDelay, InvalidCrawlDelay, setCrawlDelay)
+	parseRequestRate = makeParseDelay(parseRate, InvalidRequestRate, setRequestRate)
+}
+
+// makeParseDelay returns a parsefn that records a crawl delay for the
+// agents of the current group, using parse to interpret the value of
+// the rule, and set to record it in the group as written. Like allow
+// and disallow, a crawl-delay or request-rate rule is a member of a
+// group, so it also marks that we are within one.
+func makeParseDelay(parse func(string) (time.Duration, bool), invalid Reason, set func(g *Group, val string, d time.Duration)) parsefn {
+	return func(p *parser) parsefn {
+		p.withinGroup = true
+		if len(p.agents) == 0 {
+			p.discard(OrphanRule)
+			return parseNext
+		}
+		d, ok := parse(p.item.val)
+		if !ok {
+			if p.item.val == "" {
+				p.discard(EmptyValue)
+			} else {
+				p.discard(invalid)
+			}
+			return parseNext
+		}
+		for _, agent := range p.agents {
+			agent.group.setDelay(d)
+		}
+		set(p.group, p.item.val, d)
+		return parseNext
+	}
+}
+
+// setCrawlDelay records a crawl-delay line in g. As for the rules that
+// apply, the longest of several delays is kept.
+func setCrawlDelay(g *Group, _ string, d time.Duration) {
+	if !g.HasCrawlDelay || g.CrawlDelay < d {
+		g.CrawlDelay = d
+		g.HasCrawlDelay = true
+	}
+}
+
+// setRequestRate records a request-rate line in g. The rate with the
+// longest delay is kept.
+func setRequestRate(g *Group, val string, d time.Duration) {
+	if prev, ok := parseRate(g.RequestRate); !ok || prev < d {
+		g.RequestRate = val
+	}
+}
+
+// parseDelay interprets the value of a crawl-delay rule as a number
+// of seconds, which may be fractional, rounded to the nearest
+// nanosecond. Negative and non-finite values are rejected. Values too
+// large to represent are clamped to the longest possible duration.
+func parseDelay(s string) (time.Duration, bool) {
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) || secs < 0 {
+		return 0, false
+	}
+	if secs*float64(time.Second) >= math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	return time.Duration(math.Round(secs * float64(time.Second))), true
+}
+
+// rateUnits are the units of the period of a request-rate rule.
+var rateUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+}
+
+// parseRate interprets the value of a request-rate rule, such as
+// "1/5" or "10/1m", as the delay between requests that achieves the
+// rate: the period divided by the number of requests. The period is
+// in seconds unless it has the unit s, m or h. Anything after the rate
+// itself, such as the time of day the rate applies, is ignored.
+func parseRate(s string) (time.Duration, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	parts := strings.Split(fields[0], "/")
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	period, unit := parts[1], time.Second
+	if u, ok := rateUnits[strings.ToLower(period)[len(period)-1]]; ok {
+		period, unit = period[:len(period)-1], u
+	}
+	p, ok := parseDelay(period)
+	if !ok {
+		return 0, false
+	}
+	d := float64(p) * float64(unit/time.Second) / float64(n)
+	if d >= math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	return time.Duration(math.Round(d)), true
 }
 
 func parseSitemap(p *parser) parsefn {
 	// sitemap rules are global: they do not affect whether we are
 	// in a group or not.
-	p.robotsdata.sitemaps = append(p.robotsdata.sitemaps, p.items[0].val)
+	if p.item.val == "" {
+		p.discard(EmptyValue)
+		return parseNext
+	}
+	p.robotsdata.sitemaps = append(p.robotsdata.sitemaps, p.item.val)
 	return parseNext
 }
 
 func parseNext(p *parser) parsefn {
-	p.items = p.items[1:]
-	if len(p.items) == 0 {
+	if !p.nextItem() {
 		return parseEnd
 	}
 	return parseStart
@@ -115,5 +338,6 @@ func parseNext(p *parser) parsefn {
 
 func parseEnd(p *parser) parsefn {
 	p.robotsdata.addAgents(p.agents)
+	p.robotsdata.buildIndex()
 	return nil
 }
@@ -0,0 +1,39 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRecordEndsAtLineBreak checks that a field whose separator or
+// value is missing does not take the next line as its own, unless the
+// next line folds onto it.
+func TestRecordEndsAtLineBreak(t *testing.T) {
+	var tests = []struct {
+		input string
+		agent string
+		path  string
+		want  bool
+	}{
+		// An empty value.
+		{"user-agent: a\ndisallow:\ndisallow: /x\n", "a", "/x", false},
+		{"user-agent: a\ndisallow:  \ndisallow: /x\n", "a", "/x", false},
+		{"user-agent:\nuser-agent: b\ndisallow: /\n", "b", "/", false},
+		// A missing separator.
+		{"user-agent: a\nallow\ndisallow: /x\n", "a", "/x", false},
+		{"user-agent: a\nallow \n: /x\ndisallow: /y\n", "a", "/y", false},
+		// A folded value still continues the record.
+		{"user-agent: a\ndisallow:\n /x\n", "a", "/x", false},
+		{"user-agent: a\ndisallow:\n /x\n", "a", "/y", true},
+	}
+
+	for _, test := range tests {
+		r, err := From(200, strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("From returned error: %v", err)
+		}
+		if got := r.Test(test.agent, test.path); got != test.want {
+			t.Errorf("%q: Test(%q, %q) = %t, want %t", test.input, test.agent, test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package robots
+
+import (
+	"strings"
+)
+
+// NormalizePattern returns the simplest pattern that matches the same
+// paths as the path pattern of an allow or disallow rule.
+//
+// In a pattern, "*" matches any sequence of characters, and "$" at the
+// end of the pattern matches the end of the path. A "$" anywhere else
+// matches itself. Since a pattern need only match the beginning of a
+// path, a run of "*" is the same as one, and a "*" or "*$" at the end
+// of a pattern is redundant, so NormalizePattern collapses the one and
+// removes the other. A pattern of only wildcards becomes "*", not the
+// empty pattern, which would make an empty and so ignored rule.
+//
+// A "*" after a "$" is kept: removing it would make the "$" match the
+// end of the path rather than itself.
+//
+// NormalizePattern does not change which rule takes precedence, which
+// depends on the length of the pattern as written.
+func NormalizePattern(pattern string) string {
+	if pattern == "" {
+		return ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '*' && i > 0 && pattern[i-1] == '*' {
+			continue
+		}
+		b.WriteByte(pattern[i])
+	}
+	p := b.String()
+	if strings.HasSuffix(p, "*$") {
+		p = p[:len(p)-1]
+	}
+	if strings.HasSuffix(p, "*") && !strings.HasSuffix(p, "$*") {
+		p = p[:len(p)-1]
+	}
+	if p == "" {
+		return "*"
+	}
+	return p
+}
+
+// A pattern is the compiled form of the path of a group-member
+// record, after it is normalized. The path is split at each wildcard
+// "*" into literal segments. The first segment must begin the matched
+// path, and the others must follow it in order. If the path ends with
+// "$", the last segment must also end the matched path.
+//
+// Matching is greedy: each segment is matched at its leftmost
+// occurrence after the one before it. Because "*" matches any text,
+// an earlier occurrence never prevents a match that a later one would
+// allow, so there is no backtracking, and matching takes time linear
+// in the length of the path.
+type pattern struct {
+	segments []string
+	anchored bool // whether the last segment must end the path
+}
+
+// compilePattern normalizes and compiles path.
+func compilePattern(path string) pattern {
+	var p pattern
+	path = NormalizePattern(path)
+	if strings.HasSuffix(path, "$") {
+		path, p.anchored = path[:len(path)-1], true
+	}
+	p.segments = strings.Split(path, "*")
+	return p
+}
+
+// match reports whether p matches path.
+func (p pattern) match(path string) bool {
+	if !strings.HasPrefix(path, p.segments[0]) {
+		return false
+	}
+	last := len(p.segments) - 1
+	if last == 0 {
+		return !p.anchored || len(path) == len(p.segments[0])
+	}
+	pos := len(p.segments[0])
+	for _, seg := range p.segments[1:last] {
+		i := strings.Index(path[pos:], seg)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(seg)
+	}
+	if p.anchored {
+		return len(path)-pos >= len(p.segments[last]) &&
+			strings.HasSuffix(path, p.segments[last])
+	}
+	return strings.Contains(path[pos:], p.segments[last])
+}
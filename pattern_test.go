@@ -0,0 +1,180 @@
+package robots
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// regexpPattern compiles path to a regular expression, as a reference
+// for differential tests. Only a "$" at the end of path is special.
+func regexpPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	if anchored {
+		path = path[:len(path)-1]
+	}
+	pattern := "^" + regexp.QuoteMeta(path)
+	pattern = strings.Replace(pattern, `\*`, `.*`, -1)
+	if anchored {
+		pattern += "$"
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// strs returns every string over alphabet of length at most n.
+func strs(alphabet string, n int) []string {
+	all := []string{""}
+	prev := all
+	for i := 0; i < n; i++ {
+		var next []string
+		for _, s := range prev {
+			for _, c := range alphabet {
+				next = append(next, s+string(c))
+			}
+		}
+		all = append(all, next...)
+		prev = next
+	}
+	return all
+}
+
+func TestPatternMatch(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"", "/anything", true},
+		{"/", "/", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish*", "/fishheads", true},
+		{"/*.php", "/folder/filename.php?parameters", true},
+		{"/*.php", "/windows.PHP", false},
+		{"/*.php$", "/filename.php", true},
+		{"/*.php$", "/filename.php?parameters", false},
+		{"/fish*.php", "/fishheads/catfish.php?parameters", true},
+		{"/fish*.php", "/Fish.PHP", false},
+		{"/a*a*a", "/aa", false},
+		{"/a*a*a", "/aaa", true},
+		{"/a*b$", "/abab", true},
+		{"/a*b$", "/aba", false},
+		{"/ab*b$", "/ab", false},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+		{"/a$*", "/a", false},
+		{"/a$*", "/a$b", true},
+		{"/a$b", "/a$b", true},
+		{"/a$b", "/a", false},
+		{"/a$$", "/a$", true},
+		{"/a$$", "/a$b", false},
+		{"/a**b", "/ab", true},
+		{"/a**b", "/axyb", true},
+		{"/a*$", "/abc", true},
+		{"/a$*$", "/a$b", true},
+		{"/a$*$", "/a", false},
+		{"**", "/", true},
+		{"*$", "/", true},
+		{"*", "", true},
+		{"$", "", true},
+		{"$", "/", false},
+	}
+
+	for _, test := range tests {
+		if got := compilePattern(test.pattern).match(test.path); got != test.want {
+			t.Errorf("pattern %q matching %q = %t, want %t", test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+// TestPatternRegexp checks the matcher against regular expressions:
+// exhaustively for short patterns and paths, and at random for longer
+// ones.
+func TestPatternRegexp(t *testing.T) {
+	patterns := strs("ab*$", 5)
+	paths := strs("ab$", 5)
+	for _, pat := range patterns {
+		p, re := compilePattern(pat), regexpPattern(pat)
+		for _, path := range paths {
+			if got, want := p.match(path), re.MatchString(path); got != want {
+				t.Fatalf("pattern %q matching %q = %t, regexp gives %t", pat, path, got, want)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	random := func(alphabet string, n int) string {
+		b := make([]byte, rng.Intn(n+1))
+		for i := range b {
+			b[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+	for i := 0; i < 20000; i++ {
+		pat, path := "/"+random("ab/*$.", 12), "/"+random("ab/$.", 30)
+		if got, want := compilePattern(pat).match(path), regexpPattern(pat).MatchString(path); got != want {
+			t.Fatalf("pattern %q matching %q = %t, regexp gives %t", pat, path, got, want)
+		}
+	}
+}
+
+func TestNormalizePattern(t *testing.T) {
+	var tests = []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"/", "/"},
+		{"/fish", "/fish"},
+		{"/fish*", "/fish"},
+		{"/fish**", "/fish"},
+		{"/*.php$", "/*.php$"},
+		{"/**.php", "/*.php"},
+		{"/a***b**c", "/a*b*c"},
+		{"/a*$", "/a"},
+		{"/a**$", "/a"},
+		{"/a$b", "/a$b"},
+		{"/a$", "/a$"},
+		{"/a$$", "/a$$"},
+		{"/a$*", "/a$*"},
+		{"/a$**", "/a$*"},
+		{"/a$*$", "/a$*"},
+		{"$*$", "$*"},
+		{"$", "$"},
+		{"*", "*"},
+		{"**", "*"},
+		{"*$", "*"},
+		{"***$", "*"},
+	}
+
+	for _, test := range tests {
+		if got := NormalizePattern(test.in); got != test.want {
+			t.Errorf("NormalizePattern(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+// TestNormalizePatternRegexp checks that normalizing a pattern does
+// not change what it matches.
+func TestNormalizePatternRegexp(t *testing.T) {
+	paths := strs("ab$", 5)
+	for _, pat := range strs("ab*$", 5) {
+		re, normal := regexpPattern(pat), regexpPattern(NormalizePattern(pat))
+		for _, path := range paths {
+			if got, want := normal.MatchString(path), re.MatchString(path); got != want {
+				t.Fatalf("pattern %q matching %q = %t, normalized to %q gives %t",
+					pat, path, want, NormalizePattern(pat), got)
+			}
+		}
+	}
+}
+
+func TestPatternAllocs(t *testing.T) {
+	p := compilePattern("/*/private/*.html$")
+	allocs := testing.AllocsPerRun(100, func() {
+		p.match("/users/1234/private/messages/index.html")
+	})
+	if allocs != 0 {
+		t.Errorf("match made %v allocations, want 0", allocs)
+	}
+}
@@ -0,0 +1,156 @@
+package robots
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Status describes how the robots.txt file behind a Robots object was
+// obtained, and so which default applies to URLs it has no rules for.
+type Status int
+
+const (
+	// Parsed means the file was retrieved successfully and its
+	// rules apply.
+	Parsed Status = iota
+	// NotFound means there was no robots.txt file: the response
+	// had a 4xx status code, or was an HTML page with no valid
+	// records. All URLs are allowed.
+	NotFound
+	// Unreachable means the server failed to respond with the file
+	// because of a 5xx or 429 status code or a network error. All
+	// URLs are disallowed, unless a Cache has assumed after a long
+	// outage that there is no file; see RetryPolicy.
+	Unreachable
+	// Redirected means the request was redirected away from the
+	// file: to a page that is not a robots.txt file, or more times
+	// than a crawler will follow. All URLs are allowed.
+	Redirected
+)
+
+func (s Status) String() string {
+	switch s {
+	case Parsed:
+		return "parsed"
+	case NotFound:
+		return "not found"
+	case Unreachable:
+		return "unreachable"
+	case Redirected:
+		return "redirected"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// statusOf returns the Status corresponding to an HTTP status code.
+func statusOf(code int) Status {
+	switch {
+	case code >= 200 && code < 300:
+		return Parsed
+	case code >= 300 && code < 400:
+		return Redirected
+	case code >= 500 && code < 600, code == http.StatusTooManyRequests:
+		return Unreachable
+	}
+	return NotFound
+}
+
+// FromResponse produces a Robots object from the response to a
+// request for a robots.txt file, and closes the body of the response.
+//
+// Unlike From, FromResponse interprets the whole response: a
+// successful response at the end of a redirect chain that left the
+// robots.txt path is Redirected, and an HTML page with no valid
+// records is NotFound, as servers often answer for missing files with
+// a page and a 200 status code. The body is decoded according to its
+// Content-Encoding and the charset parameter of its Content-Type.
+// Only the first 500 KiB of the decoded body is read, unless the
+// SizeLimit option says otherwise.
+//
+// FromResponse signals an error if the body cannot be read or
+// decoded.
+func FromResponse(resp *http.Response, opts ...Option) (*Robots, error) {
+	if resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	c := makeConfig(opts)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || resp.Body == nil {
+		return makeRobots(resp.StatusCode, &robotsdata{config: c}), nil
+	}
+	if redirectedAway(resp) {
+		r := makeRobots(resp.StatusCode, &robotsdata{config: c})
+		r.status = Redirected
+		return r, nil
+	}
+
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseReader(body, c)
+	if err != nil {
+		return nil, err
+	}
+	if isHTML(resp) && len(data.groups) == 0 && len(data.sitemaps) == 0 {
+		return makeRobots(http.StatusNotFound, &robotsdata{config: c}), nil
+	}
+	return makeRobots(resp.StatusCode, data), nil
+}
+
+// redirectedAway reports whether resp is the result of a redirect
+// to a URL other than a robots.txt file.
+func redirectedAway(resp *http.Response) bool {
+	req := resp.Request
+	if req == nil || req.Response == nil || req.URL == nil {
+		return false
+	}
+	return req.URL.Path != "/robots.txt"
+}
+
+// isHTML reports whether resp declares its body to be HTML.
+func isHTML(resp *http.Response) bool {
+	mediatype, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && (mediatype == "text/html" || mediatype == "application/xhtml+xml")
+}
+
+// decodeBody returns a reader of the body of resp as UTF-8 text,
+// removing any content encoding and converting from any declared
+// charset. A body without a charset is assumed to be UTF-8.
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	var body io.Reader = resp.Body
+	var err error
+	switch enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		body, err = gzip.NewReader(body)
+	case "deflate":
+		body, err = zlib.NewReader(body)
+	default:
+		err = fmt.Errorf("unsupported content encoding: %s", enc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || params["charset"] == "" {
+		return body, nil
+	}
+	label := strings.ToLower(params["charset"])
+	if label == "utf-8" || label == "utf8" || label == "us-ascii" {
+		return body, nil
+	}
+	// An unknown charset is treated as UTF-8, in keeping with the
+	// specification's tolerance of bad input.
+	if r, err := charset.NewReaderLabel(label, body); err == nil {
+		return r, nil
+	}
+	return body, nil
+}
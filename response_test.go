@@ -0,0 +1,169 @@
+package robots
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// closeRecorder is a response body that records whether it was
+// closed.
+type closeRecorder struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestFromResponse(t *testing.T) {
+	const input = "user-agent: *\ndisallow: /private\n"
+	var tests = []struct {
+		code        int
+		contentType string
+		body        string
+		status      Status
+		want        bool // whether /private is allowed
+	}{
+		{200, "text/plain", input, Parsed, false},
+		{200, "", input, Parsed, false},
+		{200, "text/plain", "", Parsed, true},
+		{200, "text/html", input, Parsed, false},
+		{200, "text/html; charset=utf-8", "<html><body>Not found</body></html>", NotFound, true},
+		{200, "text/html", "", NotFound, true},
+		{301, "text/html", input, Redirected, true},
+		{404, "text/plain", input, NotFound, true},
+		{410, "text/plain", input, NotFound, true},
+		{429, "text/plain", input, Unreachable, false},
+		{500, "text/plain", input, Unreachable, false},
+		{503, "text/plain", input, Unreachable, false},
+	}
+
+	for _, test := range tests {
+		body := &closeRecorder{Reader: strings.NewReader(test.body)}
+		resp := &http.Response{
+			StatusCode: test.code,
+			Header:     http.Header{"Content-Type": {test.contentType}},
+			Body:       body,
+		}
+		r, err := FromResponse(resp)
+		if err != nil {
+			t.Errorf("FromResponse returned error: %v", err)
+			continue
+		}
+		if got := r.Status(); got != test.status {
+			t.Errorf("%d %q: r.Status() = %v, want %v", test.code, test.body, got, test.status)
+		}
+		if got := r.Test("Crawlerbot", "/private"); got != test.want {
+			t.Errorf("%d %q: r.Test = %t, want %t", test.code, test.body, got, test.want)
+		}
+		if !body.closed {
+			t.Errorf("%d %q: body not closed", test.code, test.body)
+		}
+	}
+}
+
+func TestFromResponseEncoding(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	fmt.Fprint(w, "user-agent: *\ndisallow: /gzip\n")
+	w.Close()
+
+	var tests = []struct {
+		header http.Header
+		body   string
+		path   string
+	}{
+		{
+			http.Header{"Content-Encoding": {"gzip"}},
+			gz.String(),
+			"/gzip",
+		},
+		{
+			http.Header{"Content-Type": {"text/plain; charset=iso-8859-1"}},
+			"user-agent: *\ndisallow: /caf\xe9\n",
+			"/café",
+		},
+		{
+			http.Header{"Content-Type": {"text/plain; charset=no-such-charset"}},
+			"user-agent: *\ndisallow: /unknown\n",
+			"/unknown",
+		},
+	}
+
+	for _, test := range tests {
+		resp := &http.Response{
+			StatusCode: 200,
+			Header:     test.header,
+			Body:       ioutil.NopCloser(strings.NewReader(test.body)),
+		}
+		r, err := FromResponse(resp)
+		if err != nil {
+			t.Errorf("FromResponse returned error: %v", err)
+			continue
+		}
+		if r.Test("Crawlerbot", test.path) {
+			t.Errorf("%v: %s should be disallowed", test.header, test.path)
+		}
+	}
+
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Encoding": {"compress"}},
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+	if _, err := FromResponse(resp); err == nil {
+		t.Errorf("unsupported content encoding should fail")
+	}
+}
+
+func TestFromResponseRedirected(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: *\ndisallow: /\n")
+	}))
+	defer target.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/moved.txt":
+			http.Redirect(w, r, target.URL+"/robots.txt", http.StatusFound)
+		default:
+			fmt.Fprint(w, "user-agent: *\ndisallow: /\n")
+		}
+	}))
+	defer ts.Close()
+
+	var tests = []struct {
+		path   string
+		status Status
+		want   bool
+	}{
+		{"/robots.txt", Redirected, true},
+		{"/moved.txt", Parsed, false},
+	}
+
+	for _, test := range tests {
+		resp, err := http.Get(ts.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := FromResponse(resp)
+		if err != nil {
+			t.Fatalf("FromResponse returned error: %v", err)
+		}
+		if got := r.Status(); got != test.status {
+			t.Errorf("%s: r.Status() = %v, want %v", test.path, got, test.status)
+		}
+		if got := r.Test("Crawlerbot", "/"); got != test.want {
+			t.Errorf("%s: r.Test = %t, want %t", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package robots
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A RetryPolicy decides when a Cache tries again to fetch a robots.txt
+// file from a server that is unreachable: one that fails to respond,
+// or responds with a 5xx or 429 status code.
+//
+// After each consecutive failure, the Cache waits longer before
+// trying again. The first wait is Initial, and each wait is Multiplier
+// times the one before, up to Max. Each wait is varied at random by up
+// to Jitter times its length, so that many crawlers do not retry at
+// once. If the server asks for a longer wait with a Retry-After
+// header, that wait is used instead, up to Max.
+//
+// RFC 9309 allows a crawler to assume there is no robots.txt file once
+// the server has been unreachable for a long time. After AssumeAllowed
+// has passed since the first of a run of failures, all URLs are
+// allowed until the server responds again. The last good copy of the
+// file is still preferred while the Cache may serve it; see
+// Cache.MaxStale.
+type RetryPolicy struct {
+	Initial    time.Duration // If zero, one minute.
+	Max        time.Duration // If zero, one hour.
+	Multiplier float64       // If less than 1, 2.
+	Jitter     float64       // A fraction of each wait; zero means none.
+
+	// AssumeAllowed is how long a server must be unreachable for
+	// all URLs to be allowed. If zero, DefaultMaxStale is used. If
+	// negative, the server's failure always applies.
+	AssumeAllowed time.Duration
+}
+
+// defaultRetryPolicy is used by a Cache without a RetryPolicy.
+var defaultRetryPolicy = RetryPolicy{Jitter: 0.1}
+
+// wait returns how long to wait after the nth consecutive failure,
+// counting from 1, given the header of the failed response, if any,
+// and the time now.
+func (p *RetryPolicy) wait(n int, h http.Header, now time.Time) time.Duration {
+	initial, max, mult := p.Initial, p.Max, p.Multiplier
+	if initial <= 0 {
+		initial = time.Minute
+	}
+	if max <= 0 {
+		max = time.Hour
+	}
+	if mult < 1 {
+		mult = 2
+	}
+	d := float64(initial) * math.Pow(mult, float64(n-1))
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if after, ok := retryAfter(h, now); ok && float64(after) > d {
+		d = float64(after)
+	}
+	if d > float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// assumeAllowed reports whether a server that has been unreachable
+// for d should be assumed to have no robots.txt file.
+func (p *RetryPolicy) assumeAllowed(d time.Duration) bool {
+	switch {
+	case p.AssumeAllowed < 0:
+		return false
+	case p.AssumeAllowed == 0:
+		return d >= DefaultMaxStale
+	}
+	return d >= p.AssumeAllowed
+}
+
+// retryAfter returns the wait requested by the Retry-After header in
+// h, measured from now if it is a date, and whether there is a valid
+// one.
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		if secs > math.MaxInt64/int64(time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// HostState describes what a Cache knows about the robots.txt file of
+// a host.
+type HostState struct {
+	URL     string    // URL of the robots.txt file.
+	Status  Status    // Status of the file being served.
+	Fetched time.Time // When the file being served was fetched.
+	Expires time.Time // When the file will next be fetched.
+
+	// Failures is the number of consecutive failed fetches, and
+	// FailingSince the time of the first of them. If the last
+	// fetch succeeded, Failures is 0 and FailingSince is zero.
+	Failures     int
+	FailingSince time.Time
+
+	// If the last fetch failed, StatusCode is the status code of
+	// its response, or 0 if there was none, and Err is the error
+	// that prevented it, if any.
+	StatusCode int
+	Err        error
+
+	// Stale is true if the file being served is the last good
+	// copy, kept while the server is unreachable. Assumed is true
+	// if the server has been unreachable long enough that all URLs
+	// are allowed.
+	Stale   bool
+	Assumed bool
+}
@@ -0,0 +1,203 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyWait(t *testing.T) {
+	p := &RetryPolicy{Initial: time.Second, Max: time.Minute, Multiplier: 3}
+	var tests = []struct {
+		n      int
+		header http.Header
+		want   time.Duration
+	}{
+		{1, nil, time.Second},
+		{2, nil, 3 * time.Second},
+		{3, nil, 9 * time.Second},
+		{5, nil, time.Minute},
+		{1000, nil, time.Minute},
+		{1, http.Header{"Retry-After": {"30"}}, 30 * time.Second},
+		{3, http.Header{"Retry-After": {"2"}}, 9 * time.Second},
+		{1, http.Header{"Retry-After": {"3600"}}, time.Minute},
+		{1, http.Header{"Retry-After": {"soon"}}, time.Second},
+		{1, http.Header{"Retry-After": {"-5"}}, time.Second},
+	}
+
+	for _, test := range tests {
+		if got := p.wait(test.n, test.header, time.Now()); got != test.want {
+			t.Errorf("wait(%d, %v) = %v, want %v", test.n, test.header, got, test.want)
+		}
+	}
+
+	var zero RetryPolicy
+	if got := zero.wait(1, nil, time.Now()); got != time.Minute {
+		t.Errorf("zero policy: wait(1) = %v, want %v", got, time.Minute)
+	}
+	if got := zero.wait(10, nil, time.Now()); got != time.Hour {
+		t.Errorf("zero policy: wait(10) = %v, want %v", got, time.Hour)
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	p := &RetryPolicy{Initial: 10 * time.Second, Jitter: 0.5}
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		d := p.wait(1, nil, time.Now())
+		if d < 5*time.Second || d > 15*time.Second {
+			t.Fatalf("wait(1) = %v, want within 5s of 10s", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("jitter did not vary the wait")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour).Format(http.TimeFormat)
+	past := now.Add(-time.Hour).Format(http.TimeFormat)
+	if d, ok := retryAfter(http.Header{"Retry-After": {future}}, now); !ok || d != time.Hour {
+		t.Errorf("retryAfter(%q) = %v, %t", future, d, ok)
+	}
+	if d, ok := retryAfter(http.Header{"Retry-After": {past}}, now); !ok || d != 0 {
+		t.Errorf("retryAfter(%q) = %v, %t", past, d, ok)
+	}
+	if _, ok := retryAfter(nil, now); ok {
+		t.Errorf("retryAfter(nil) reported a wait")
+	}
+}
+
+func TestCacheRetry(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	atomic.StoreInt32(&s.status, http.StatusServiceUnavailable)
+	c, clock := newTestCache()
+	c.Retry = &RetryPolicy{Initial: time.Minute, Max: 10 * time.Minute, AssumeAllowed: -1}
+	ctx := context.Background()
+	start := clock.Now()
+
+	// Fetches wait 1, 2, 4, 8 and then 10 minutes after each
+	// failure.
+	var steps = []struct {
+		advance  time.Duration
+		requests int32
+	}{
+		{0, 1},
+		{59 * time.Second, 1},
+		{time.Second, 2},
+		{time.Minute, 2},
+		{time.Minute, 3},
+		{4 * time.Minute, 4},
+		{8 * time.Minute, 5},
+		{10 * time.Minute, 6},
+	}
+	for i, step := range steps {
+		clock.advance(step.advance)
+		res, _ := c.Fetch(ctx, s.URL)
+		if s.requests != step.requests {
+			t.Errorf("step %d: got %d requests, want %d", i, s.requests, step.requests)
+		}
+		if res.Test("Crawlerbot", "/public") {
+			t.Errorf("step %d: unreachable server allowed", i)
+		}
+	}
+
+	state, ok := c.State(s.URL + "/page")
+	if !ok {
+		t.Fatalf("no state for %s", s.URL)
+	}
+	if state.Failures != 6 || !state.FailingSince.Equal(start) || state.StatusCode != 503 ||
+		state.Status != Unreachable || state.Stale || state.Assumed {
+		t.Errorf("state = %+v", state)
+	}
+
+	// Success ends the run of failures.
+	atomic.StoreInt32(&s.status, 0)
+	clock.advance(10 * time.Minute)
+	c.Fetch(ctx, s.URL)
+	if state, _ := c.State(s.URL); state.Failures != 0 || !state.FailingSince.IsZero() || state.Status != Parsed {
+		t.Errorf("state after recovery = %+v", state)
+	}
+
+	// A 429 response is also a failure, and a longer wait asked
+	// for by Retry-After is honored.
+	atomic.StoreInt32(&s.status, http.StatusTooManyRequests)
+	s.header.Set("Retry-After", "300")
+	clock.advance(DefaultTTL)
+	res, _ := c.Fetch(ctx, s.URL)
+	if want := clock.Now().Add(5 * time.Minute); !res.Expires.Equal(want) {
+		t.Errorf("429: expires %v, want %v", res.Expires, want)
+	}
+	if state, _ := c.State(s.URL); state.Failures != 1 || state.StatusCode != 429 || !state.Stale {
+		t.Errorf("state after 429 = %+v", state)
+	}
+	if states := c.States(); len(states) != 1 || states[0].URL != s.URL+"/robots.txt" {
+		t.Errorf("c.States() = %+v", states)
+	}
+}
+
+// TestCacheRetryAfterDate checks that a Retry-After date is measured
+// from the time of the Cache.
+func TestCacheRetryAfterDate(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	atomic.StoreInt32(&s.status, http.StatusServiceUnavailable)
+	c, clock := newTestCache()
+	c.Retry = &RetryPolicy{Initial: time.Minute, Max: time.Hour}
+	s.header.Set("Retry-After", clock.Now().Add(7*time.Minute).Format(http.TimeFormat))
+
+	res, _ := c.Fetch(context.Background(), s.URL)
+	if want := clock.Now().Add(7 * time.Minute); !res.Expires.Equal(want) {
+		t.Errorf("expires %v, want %v", res.Expires, want)
+	}
+}
+
+func TestCacheAssumeAllowed(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	atomic.StoreInt32(&s.status, http.StatusInternalServerError)
+	c, clock := newTestCache()
+	c.Retry = &RetryPolicy{Initial: time.Hour, Max: time.Hour, AssumeAllowed: 48 * time.Hour}
+	ctx := context.Background()
+
+	for i := 0; i <= 48; i++ {
+		res, _ := c.Fetch(ctx, s.URL)
+		if got, want := res.Test("Crawlerbot", "/private"), i == 48; got != want {
+			t.Errorf("after %d hours: allowed = %t, want %t", i, got, want)
+		}
+		clock.advance(time.Hour)
+	}
+	if state, _ := c.State(s.URL); !state.Assumed || state.Status != Unreachable {
+		t.Errorf("state = %+v", state)
+	}
+
+	// When the server recovers, its file is used again.
+	atomic.StoreInt32(&s.status, 0)
+	res, _ := c.Fetch(ctx, s.URL)
+	if res.Test("Crawlerbot", "/private") || !res.Test("Crawlerbot", "/public") {
+		t.Errorf("recovered file not used")
+	}
+}
+
+func TestCacheStaleState(t *testing.T) {
+	s := newRobotsServer()
+	defer s.Close()
+	c, clock := newTestCache()
+	ctx := context.Background()
+
+	c.Fetch(ctx, s.URL)
+	atomic.StoreInt32(&s.status, http.StatusBadGateway)
+	clock.advance(DefaultTTL)
+	c.Fetch(ctx, s.URL)
+	if state, _ := c.State(s.URL); !state.Stale || state.Status != Parsed || state.Failures != 1 {
+		t.Errorf("state = %+v", state)
+	}
+	if _, ok := c.State("http://unknown.example.com/"); ok {
+		t.Errorf("state reported for unknown host")
+	}
+}
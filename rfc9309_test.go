@@ -0,0 +1,247 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+)
+
+// The tests in this file follow the sections of RFC 9309:
+// https://www.rfc-editor.org/rfc/rfc9309.
+
+type rfcTest struct {
+	name string
+	path string
+	want bool
+}
+
+func testRFC9309(t *testing.T, status int, input string, tests []rfcTest) {
+	t.Helper()
+	r, err := From(status, strings.NewReader(input), RFC9309())
+	if err != nil {
+		t.Fatalf("From returned error: %v", err)
+	}
+	for _, test := range tests {
+		if got := r.Test(test.name, test.path); got != test.want {
+			t.Errorf("r.Test(%q, %q) = %t, want %t", test.name, test.path, got, test.want)
+		}
+		if d := r.Explain(test.name, test.path); d.Allowed != test.want {
+			t.Errorf("r.Explain(%q, %q).Allowed = %t, want %t", test.name, test.path, d.Allowed, test.want)
+		}
+	}
+}
+
+// Section 2.2.1: The User-Agent Line.
+func TestRFC9309UserAgentLine(t *testing.T) {
+	input := strings.Join([]string{
+		"user-agent: ExampleBot",
+		"disallow: /foo",
+		"",
+		"user-agent: googlebot",
+		"disallow: /g",
+		"",
+		"user-agent: *",
+		"disallow: /star",
+		"",
+		"user-agent: examplebot",
+		"disallow: /bar",
+	}, "\n")
+
+	testRFC9309(t, 200, input, []rfcTest{
+		// Product tokens are compared without regard to case.
+		{"examplebot", "/foo", false},
+		{"EXAMPLEBOT", "/foo", false},
+		// A version is not part of the product token.
+		{"ExampleBot/1.0", "/foo", false},
+		// Groups naming the same agent are combined.
+		{"ExampleBot", "/bar", false},
+		{"ExampleBot", "/star", true},
+		// A token is matched exactly, not by prefix.
+		{"Googlebot-Image", "/g", true},
+		{"Googlebot-Image", "/star", false},
+		// An agent matching no group uses the "*" group.
+		{"OtherBot", "/star", false},
+		{"OtherBot", "/foo", true},
+	})
+}
+
+// Section 2.2.1: if there is no group for "*" and no other group
+// matches, there are no rules.
+func TestRFC9309NoMatchingGroup(t *testing.T) {
+	testRFC9309(t, 200, "user-agent: a\ndisallow: /", []rfcTest{
+		{"b", "/", true},
+	})
+}
+
+// Section 2.2.2: The "Allow" and "Disallow" Lines.
+func TestRFC9309AllowDisallow(t *testing.T) {
+	input := strings.Join([]string{
+		"user-agent: *",
+		"disallow: /example/page/disallowed.gif",
+		"allow: /example/page/",
+		"disallow: /same",
+		"allow: /same",
+		"allow: /emit",
+		"disallow: /emit",
+		"disallow:",
+		"allow: /foo/bar/ツ",
+		"disallow: /foo/bar/%E3%83%84/",
+		"disallow: /baz/%62%61%7A",
+		"disallow: /qux/a%2fb",
+		"disallow: /lower/%e3%83%84",
+		"disallow: /robots.txt",
+	}, "\n")
+
+	testRFC9309(t, 200, input, []rfcTest{
+		// The most specific match wins.
+		{"ExampleBot", "/example/page/", true},
+		{"ExampleBot", "/example/page/disallowed.gif", false},
+		// Equally specific allow and disallow: allow wins,
+		// whichever comes first.
+		{"ExampleBot", "/same", true},
+		{"ExampleBot", "/emit", true},
+		// An empty disallow rule matches nothing.
+		{"ExampleBot", "/other", true},
+		// Characters outside US-ASCII are compared
+		// percent-encoded.
+		{"ExampleBot", "/foo/bar/%E3%83%84", true},
+		{"ExampleBot", "/foo/bar/ツ/", false},
+		{"ExampleBot", "/foo/bar/%e3%83%84/", false},
+		{"ExampleBot", "/lower/ツ", false},
+		// Percent-encoded unreserved characters are decoded,
+		// in both patterns and paths, and others are not.
+		{"ExampleBot", "/baz/%62%61%7A", false},
+		{"ExampleBot", "/baz/baz", false},
+		{"ExampleBot", "/baz/ba%7a", false},
+		{"ExampleBot", "/qux/a/b", true},
+		{"ExampleBot", "/qux/a%2Fb", false},
+		// The /robots.txt URL is implicitly allowed.
+		{"ExampleBot", "/robots.txt", true},
+		{"ExampleBot", "http://example.com/robots.txt", true},
+	})
+}
+
+// Section 2.2.2: patterns that match the same octets are equally
+// specific, however they are written, so allow wins the tie.
+func TestRFC9309EncodedPrecedence(t *testing.T) {
+	var tests = []struct {
+		rules string
+		paths []string
+	}{
+		{"allow: /a/ツ\ndisallow: /a/%E3%83%84", []string{"/a/ツ", "/a/%E3%83%84"}},
+		{"disallow: /a/%E3%83%84\nallow: /a/ツ", []string{"/a/ツ", "/a/%E3%83%84"}},
+		{"allow: /a/%62\ndisallow: /a/b", []string{"/a/b", "/a/%62"}},
+		{"disallow: /a/b\nallow: /a/%62", []string{"/a/b", "/a/%62"}},
+	}
+
+	for _, test := range tests {
+		r, _ := From(200, strings.NewReader("user-agent: *\n"+test.rules), RFC9309())
+		for _, path := range test.paths {
+			if d := r.Explain("ExampleBot", path); d.Default || !d.Allow {
+				t.Errorf("%q: Explain(%q) = %+v, want the allow rule", test.rules, path, d)
+			}
+		}
+	}
+}
+
+// Section 2.2.3: Special Characters.
+func TestRFC9309SpecialCharacters(t *testing.T) {
+	input := strings.Join([]string{
+		"user-agent: *   # comment",
+		"allow: /path/file-with-a-#-character # a comment",
+		"disallow: /path/",
+		"allow: /path/*.gif$",
+		"disallow: /$",
+		"allow: /",
+	}, "\n")
+
+	testRFC9309(t, 200, input, []rfcTest{
+		// "#" begins a comment, even in a pattern.
+		{"ExampleBot", "/path/file-with-a-", true},
+		{"ExampleBot", "/path/file", false},
+		// "*" matches any sequence of characters.
+		{"ExampleBot", "/path/a/b.gif", true},
+		// "$" matches the end of the path.
+		{"ExampleBot", "/path/a/b.gif?x", false},
+		{"ExampleBot", "/", false},
+		{"ExampleBot", "/index.html", true},
+	})
+}
+
+// Section 2.2.4: Other Records. Records other than user-agent,
+// allow and disallow do not affect groups.
+func TestRFC9309OtherRecords(t *testing.T) {
+	input := strings.Join([]string{
+		"user-agent: a",
+		"sitemap: https://example.com/sitemap.xml",
+		"disallow: /",
+	}, "\n")
+
+	testRFC9309(t, 200, input, []rfcTest{
+		{"a", "/", false},
+	})
+}
+
+// Sections 2.3.1.1, 2.3.1.3 and 2.3.1.4: Successful Access,
+// "Unavailable" Status and "Unreachable" Status.
+func TestRFC9309AccessResults(t *testing.T) {
+	input := "user-agent: *\ndisallow: /private"
+
+	testRFC9309(t, 200, input, []rfcTest{
+		{"ExampleBot", "/private", false},
+		{"ExampleBot", "/public", true},
+	})
+	for _, status := range []int{400, 401, 403, 404, 410} {
+		testRFC9309(t, status, input, []rfcTest{
+			{"ExampleBot", "/private", true},
+		})
+	}
+	for _, status := range []int{500, 502, 503} {
+		testRFC9309(t, status, input, []rfcTest{
+			{"ExampleBot", "/public", false},
+			// No agent matches, but /robots.txt is still
+			// implicitly allowed.
+			{"ExampleBot", "/robots.txt", true},
+		})
+	}
+}
+
+// Section 2.3.1.5: Parsing Errors. Invalid lines are ignored, and
+// valid lines are used.
+func TestRFC9309ParsingErrors(t *testing.T) {
+	input := strings.Join([]string{
+		"<html>",
+		"user-agent: *",
+		"<p>nonsense</p>",
+		"disallow: /private",
+		"noindex: /public",
+	}, "\n")
+
+	testRFC9309(t, 200, input, []rfcTest{
+		{"ExampleBot", "/private", false},
+		{"ExampleBot", "/public", true},
+	})
+}
+
+func TestPercentEncode(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"/foo/bar", "/foo/bar"},
+		{"/foo/bar/ツ", "/foo/bar/%E3%83%84"},
+		{"/foo/bar/%e3%83%84", "/foo/bar/%E3%83%84"},
+		{"/100%", "/100%"},
+		{"/%zz", "/%zz"},
+		{"/%a", "/%a"},
+		{"/foo/bar/%62%61%7A", "/foo/bar/baz"},
+		{"/%7e%2D%2e%5F", "/~-._"},
+		{"/a%2fb", "/a%2Fb"},
+		{"/%2a%24", "/%2A%24"},
+	}
+
+	for _, test := range tests {
+		if got := percentEncode(test.input); got != test.want {
+			t.Errorf("percentEncode(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
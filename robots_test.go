@@ -1,8 +1,11 @@
 package robots
 
 import (
+	"math"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAgentPrecedence(t *testing.T) {
@@ -18,7 +21,9 @@ func TestAgentPrecedence(t *testing.T) {
 	}{
 		{"Googlebot-News", "googlebot-news"},
 		{"Googlebot", "googlebot"},
-		{"Googlebot-Image", "googlebot"},
+		{"Googlebot/2.1", "googlebot"},
+		{"Googlebot-Image", "*"},
+		{"Googlebot-News-Extra", "*"},
 		{"Bingbot", "*"},
 	}
 
@@ -34,6 +39,36 @@ func TestAgentPrecedence(t *testing.T) {
 	}
 }
 
+func TestAgentPrefixMatching(t *testing.T) {
+	fname := "testdata/agent_precedence.txt"
+	data, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("couldn't open test data %s", fname)
+	}
+
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"Googlebot-News", "googlebot-news"},
+		{"Googlebot", "googlebot"},
+		{"Googlebot-Image", "googlebot"},
+		{"Googlebot-News-Extra", "googlebot-news"},
+		{"Bingbot", "*"},
+	}
+
+	r, err := From(200, data, PrefixAgentMatching())
+	if err != nil {
+		t.Errorf("couldn't read from test data %s", fname)
+	}
+
+	for _, test := range tests {
+		if got, _ := r.bestAgent(test.input); got.name != test.want {
+			t.Errorf("r.bestAgent(%q).name = %v", test.input, got.name)
+		}
+	}
+}
+
 func TestGrouping(t *testing.T) {
 	fname := "testdata/grouping.txt"
 	data, err := os.Open(fname)
@@ -72,6 +107,47 @@ func TestGrouping(t *testing.T) {
 	}
 }
 
+func TestMergedGroups(t *testing.T) {
+	fname := "testdata/merged_groups.txt"
+	data, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("couldn't open test data %s", fname)
+	}
+
+	var tests = []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"googlebot", "/a", false},
+		{"googlebot", "/a/ok", true},
+		{"googlebot", "/b", false},
+		{"googlebot", "/c", true},
+		{"bingbot", "/a", true},
+		{"bingbot", "/b", false},
+		{"bingbot", "/c", true},
+		{"otherbot", "/a", true},
+		{"otherbot", "/c", false},
+	}
+
+	r, err := From(200, data)
+	if err != nil {
+		t.Errorf("couldn't read from test data %s", fname)
+	}
+
+	for _, test := range tests {
+		if got := r.Test(test.name, test.path); got != test.want {
+			t.Errorf("r.Test(%q, %q) = %t", test.name, test.path, got)
+		}
+	}
+	if d, _ := r.CrawlDelay("googlebot"); d != 5*time.Second {
+		t.Errorf("r.CrawlDelay(\"googlebot\") = %v, want 5s", d)
+	}
+	if got := len(r.agents); got != 3 {
+		t.Errorf("merged groups have %d agents, want 3", got)
+	}
+}
+
 func TestPathMatching(t *testing.T) {
 	var tests = []struct {
 		source string
@@ -128,7 +204,7 @@ func TestPathMatching(t *testing.T) {
 		m := &member{
 			path: test.source,
 		}
-		m.compile()
+		m.compile(config{})
 		if got := m.match(test.test); got != test.want {
 			t.Errorf(
 				"for pattern %q, m.match(%q) = %t",
@@ -170,6 +246,160 @@ func TestMemberPrecedence(t *testing.T) {
 	}
 }
 
+func TestCrawlDelay(t *testing.T) {
+	fname := "testdata/crawl_delay.txt"
+	data, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("couldn't open test data %s", fname)
+	}
+
+	var tests = []struct {
+		name  string
+		want  time.Duration
+		found bool
+	}{
+		{"Crawlerbot", 2500 * time.Millisecond, true},
+		{"slowbot", 30 * time.Second, true},
+		{"sluggishbot", 30 * time.Second, true},
+		{"badbot", 0, false},
+		{"ratebot", 5 * time.Second, true},
+		{"mixedbot", 10 * time.Second, true},
+		{"nodelaybot", 0, false},
+		{"otherbot", time.Second, true},
+	}
+
+	r, err := From(200, data)
+	if err != nil {
+		t.Errorf("couldn't read from test data %s", fname)
+	}
+
+	for _, test := range tests {
+		got, found := r.CrawlDelay(test.name)
+		if got != test.want || found != test.found {
+			t.Errorf("r.CrawlDelay(%q) = %v, %t, want %v, %t",
+				test.name, got, found, test.want, test.found)
+		}
+	}
+	if r.Test("crawlerbot", "/private") {
+		t.Errorf("crawl-delay should not end the crawlerbot group")
+	}
+}
+
+func TestParseDelay(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{"10", 10 * time.Second, true},
+		{"0.5", 500 * time.Millisecond, true},
+		{"0", 0, true},
+		{"1e400", 0, false},
+		{"1e300", time.Duration(math.MaxInt64), true},
+		{"-3", 0, false},
+		{"NaN", 0, false},
+		{"10s", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseDelay(test.input)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseDelay(%q) = %v, %t, want %v, %t",
+				test.input, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{"1/5", 5 * time.Second, true},
+		{"1/5s", 5 * time.Second, true},
+		{"10/1m", 6 * time.Second, true},
+		{"3/1H", 20 * time.Minute, true},
+		{"4/1", 250 * time.Millisecond, true},
+		{"1/0.5", 500 * time.Millisecond, true},
+		{"1/10 0600-0845", 10 * time.Second, true},
+		{"0/5", 0, false},
+		{"-1/5", 0, false},
+		{"1/", 0, false},
+		{"1/s", 0, false},
+		{"/5", 0, false},
+		{"1/5d", 0, false},
+		{"1/2/3", 0, false},
+		{"5", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseRate(test.input)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseRate(%q) = %v, %t, want %v, %t",
+				test.input, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	fname := "testdata/member_precedence.txt"
+	data, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("couldn't open test data %s", fname)
+	}
+
+	var tests = []struct {
+		input string
+		want  Decision
+	}{
+		{"/page", Decision{Allowed: true, Agent: "*", Path: "/page",
+			Allow: true, Pattern: "/page", Line: 4}},
+		{"/folder/page", Decision{Allowed: false, Agent: "*", Path: "/folder/page",
+			Allow: false, Pattern: "/folder", Line: 6}},
+		{"/file.htm?q=1", Decision{Allowed: false, Agent: "*", Path: "/file.htm?q=1",
+			Allow: false, Pattern: "/*.htm", Line: 7}},
+		{"http://example.com", Decision{Allowed: true, Agent: "*", Path: "/",
+			Allow: true, Pattern: "/$", Line: 5}},
+		{"/other", Decision{Allowed: false, Agent: "*", Path: "/other",
+			Allow: false, Pattern: "/", Line: 8}},
+		{"%", Decision{Allowed: true, Agent: "*", Default: true}},
+	}
+
+	r, err := From(200, data)
+	if err != nil {
+		t.Errorf("couldn't read from test data %s", fname)
+	}
+
+	for _, test := range tests {
+		got := r.Explain("crawler", test.input)
+		if got != test.want {
+			t.Errorf("r.Explain(\"crawler\", %q) = %+v, want %+v",
+				test.input, got, test.want)
+		}
+		if got.Allowed != r.Test("crawler", test.input) {
+			t.Errorf("r.Explain(\"crawler\", %q) disagrees with r.Test",
+				test.input)
+		}
+	}
+}
+
+func TestExplainDefault(t *testing.T) {
+	r, _ := From(503, nil)
+	want := Decision{Allowed: false, Path: "/page", Default: true}
+	if got := r.Explain("crawler", "/page"); got != want {
+		t.Errorf("r.Explain on 503 = %+v, want %+v", got, want)
+	}
+
+	r, _ = From(200, strings.NewReader("user-agent: a\ndisallow: /"))
+	want = Decision{Allowed: true, Path: "/page", Default: true}
+	if got := r.Explain("b", "/page"); got != want {
+		t.Errorf("r.Explain for unmatched agent = %+v, want %+v", got, want)
+	}
+}
+
 func TestLocate(t *testing.T) {
 	var tests = []struct {
 		robots string
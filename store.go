@@ -0,0 +1,125 @@
+package robots
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// A Store keeps results for a Cache, so that their number can be
+// bounded, or so that they can outlive it or be shared between
+// processes. Keys are robots.txt URLs, as returned by Locate. A Cache
+// with a Store gets a result from it each time the result is needed.
+//
+// A Store must be safe for concurrent use by multiple goroutines. The
+// package storetest checks that an implementation behaves as a Cache
+// expects.
+type Store interface {
+	// Get returns the result stored under key, or nil if there
+	// is none or it has expired.
+	Get(key string) (*Result, error)
+
+	// Put stores res under key until expires, replacing any
+	// result already stored under key.
+	Put(key string, res *Result, expires time.Time) error
+
+	// Delete removes the result stored under key, if there is
+	// one.
+	Delete(key string) error
+}
+
+// A MemoryStore is a Store that keeps results in memory. When it is
+// full, the least recently used result is evicted.
+type MemoryStore struct {
+	// Clock is used to tell whether results have expired. If nil,
+	// the time package is used. A Cache with an injected clock
+	// needs a Store using the same one.
+	Clock Clock
+
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *memoryEntry, most recently used first
+}
+
+type memoryEntry struct {
+	key     string
+	res     *Result
+	expires time.Time
+}
+
+// NewMemoryStore returns a MemoryStore holding at most max results. If
+// max is not positive, the number of results is not limited.
+func NewMemoryStore(max int) *MemoryStore {
+	return &MemoryStore{
+		max:     max,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(key string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !clockNow(s.Clock).Before(e.expires) {
+		s.remove(el)
+		return nil, nil
+	}
+	s.lru.MoveToFront(el)
+	return e.res, nil
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(key string, res *Result, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value = &memoryEntry{key, res, expires}
+		s.lru.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{key, res, expires})
+	if s.max > 0 && s.lru.Len() > s.max {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+// Len returns the number of results in s, including any that have
+// expired but not yet been removed.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// clockNow returns the time according to c, or the time package if c
+// is nil.
+func clockNow(c Clock) time.Time {
+	if c != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,58 @@
+package robots_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benjaminestes/robots"
+	"github.com/benjaminestes/robots/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func() robots.Store {
+		return robots.NewMemoryStore(0)
+	})
+	storetest.Run(t, func() robots.Store {
+		return robots.NewMemoryStore(100)
+	})
+}
+
+func TestFileStoreConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "robots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storetest.Run(t, func() robots.Store {
+		sub, err := ioutil.TempDir(dir, "store")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &robots.FileStore{Dir: sub}
+	})
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	s := robots.NewMemoryStore(2)
+	r, _ := robots.From(200, strings.NewReader(""))
+	expires := time.Now().Add(time.Hour)
+	keys := []string{"http://a/robots.txt", "http://b/robots.txt", "http://c/robots.txt"}
+
+	s.Put(keys[0], &robots.Result{Robots: r, URL: keys[0]}, expires)
+	s.Put(keys[1], &robots.Result{Robots: r, URL: keys[1]}, expires)
+	// Using a makes b the least recently used.
+	s.Get(keys[0])
+	s.Put(keys[2], &robots.Result{Robots: r, URL: keys[2]}, expires)
+
+	if s.Len() != 2 {
+		t.Errorf("s.Len() = %d, want 2", s.Len())
+	}
+	for i, want := range []bool{true, false, true} {
+		if res, _ := s.Get(keys[i]); (res != nil) != want {
+			t.Errorf("Get(%q) = %v, want present %t", keys[i], res, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package storetest checks implementations of robots.Store.
+//
+// A test of an implementation calls Run with a function returning
+// empty stores:
+//
+//	func TestMyStore(t *testing.T) {
+//		storetest.Run(t, func() robots.Store {
+//			return NewMyStore()
+//		})
+//	}
+package storetest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benjaminestes/robots"
+)
+
+const key = "https://example.com/robots.txt"
+
+// Run checks that stores returned by newStore behave as a
+// robots.Cache expects. Each check runs as a subtest, with a new
+// store.
+func Run(t *testing.T, newStore func() robots.Store) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, robots.Store)
+	}{
+		{"Missing", testMissing},
+		{"PutGet", testPutGet},
+		{"Replace", testReplace},
+		{"Delete", testDelete},
+		{"Expired", testExpired},
+		{"Keys", testKeys},
+		{"Concurrent", testConcurrent},
+	}
+	for _, test := range tests {
+		fn := test.fn
+		t.Run(test.name, func(t *testing.T) {
+			fn(t, newStore())
+		})
+	}
+}
+
+// result returns a result for key whose robots.txt file disallows
+// path.
+func result(t *testing.T, key, path string) *robots.Result {
+	r, err := robots.From(200, strings.NewReader("user-agent: *\ndisallow: "+path+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &robots.Result{
+		Robots:     r,
+		URL:        key,
+		FinalURL:   key,
+		StatusCode: 200,
+		Fetched:    time.Now().Round(0),
+		Expires:    time.Now().Add(time.Hour).Round(0),
+		ETag:       `"` + path + `"`,
+	}
+}
+
+// get gets key from s, failing t on error.
+func get(t *testing.T, s robots.Store, key string) *robots.Result {
+	t.Helper()
+	res, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("Get(%q) returned error: %v", key, err)
+	}
+	return res
+}
+
+// put puts res under key in s for an hour, failing t on error.
+func put(t *testing.T, s robots.Store, key string, res *robots.Result) {
+	t.Helper()
+	if err := s.Put(key, res, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Put(%q) returned error: %v", key, err)
+	}
+}
+
+// check fails t unless got is a result equivalent to want, whose
+// robots.txt file disallows path.
+func check(t *testing.T, got, want *robots.Result, path string) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("got no result, want %+v", want)
+	}
+	if got.URL != want.URL || got.StatusCode != want.StatusCode || got.ETag != want.ETag ||
+		!got.Fetched.Equal(want.Fetched) || !got.Expires.Equal(want.Expires) {
+		t.Errorf("got result %+v, want %+v", got, want)
+	}
+	if got.Robots == nil {
+		t.Fatalf("got result without Robots")
+	}
+	if got.Test("Crawlerbot", path) || !got.Test("Crawlerbot", "/other") {
+		t.Errorf("result does not disallow only %s", path)
+	}
+}
+
+func testMissing(t *testing.T, s robots.Store) {
+	if res := get(t, s, key); res != nil {
+		t.Errorf("Get of missing key returned %+v", res)
+	}
+}
+
+func testPutGet(t *testing.T, s robots.Store) {
+	want := result(t, key, "/a")
+	put(t, s, key, want)
+	check(t, get(t, s, key), want, "/a")
+	// Getting a result does not remove it.
+	check(t, get(t, s, key), want, "/a")
+}
+
+func testReplace(t *testing.T, s robots.Store) {
+	put(t, s, key, result(t, key, "/a"))
+	want := result(t, key, "/b")
+	put(t, s, key, want)
+	check(t, get(t, s, key), want, "/b")
+}
+
+func testDelete(t *testing.T, s robots.Store) {
+	put(t, s, key, result(t, key, "/a"))
+	if err := s.Delete(key); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if res := get(t, s, key); res != nil {
+		t.Errorf("Get after Delete returned %+v", res)
+	}
+	if err := s.Delete(key); err != nil {
+		t.Errorf("Delete of missing key returned error: %v", err)
+	}
+}
+
+func testExpired(t *testing.T, s robots.Store) {
+	if err := s.Put(key, result(t, key, "/a"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	if res := get(t, s, key); res != nil {
+		t.Errorf("Get of expired result returned %+v", res)
+	}
+
+	// The expiry of a result is that of the latest Put.
+	put(t, s, key, result(t, key, "/a"))
+	if err := s.Put(key, result(t, key, "/b"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	if res := get(t, s, key); res != nil {
+		t.Errorf("Get of expired result returned %+v", res)
+	}
+}
+
+func testKeys(t *testing.T, s robots.Store) {
+	keys := []string{
+		"https://example.com/robots.txt",
+		"http://example.com/robots.txt",
+		"https://example.com:8443/robots.txt",
+		"https://www.example.com/robots.txt",
+	}
+	var want []*robots.Result
+	for i, key := range keys {
+		res := result(t, key, fmt.Sprintf("/%d", i))
+		want = append(want, res)
+		put(t, s, key, res)
+	}
+	s.Delete(keys[0])
+	for i, key := range keys[1:] {
+		check(t, get(t, s, key), want[i+1], fmt.Sprintf("/%d", i+1))
+	}
+}
+
+func testConcurrent(t *testing.T, s robots.Store) {
+	const n = 8
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			own := fmt.Sprintf("https://%d.example.com/robots.txt", i)
+			for j := 0; j < 20; j++ {
+				for _, key := range []string{key, own} {
+					if err := s.Put(key, result(t, key, "/a"), time.Now().Add(time.Hour)); err != nil {
+						t.Errorf("Put returned error: %v", err)
+					}
+					if _, err := s.Get(key); err != nil {
+						t.Errorf("Get returned error: %v", err)
+					}
+				}
+				if j%5 == 0 {
+					if err := s.Delete(key); err != nil {
+						t.Errorf("Delete returned error: %v", err)
+					}
+				}
+			}
+			if res, err := s.Get(own); err != nil || res == nil || res.URL != own {
+				t.Errorf("Get(%q) = %+v, %v", own, res, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,75 @@
+package robots
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that refuses requests disallowed
+// by robots.txt. Before each request, it gets the robots.txt file for
+// the URL from Cache, and tests the URL against it. An allowed request
+// is passed to Base; a disallowed request fails with a
+// *DisallowedError.
+//
+// Requests for robots.txt files themselves are always allowed, as are
+// the redirects of requests made by a Fetcher, so a Cache may fetch
+// files through a client using the Transport.
+type Transport struct {
+	// Base makes allowed requests. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Agent is the name tested against robots.txt files. If empty,
+	// the product token of the User-Agent header of each request
+	// is used; see ProductToken.
+	Agent string
+
+	// Cache provides robots.txt files. It must not be nil.
+	Cache *Cache
+}
+
+// A DisallowedError is returned by Transport for a request that
+// robots.txt does not allow.
+type DisallowedError struct {
+	URL      string   // URL of the request.
+	Agent    string   // Name tested against robots.txt.
+	Decision Decision // How the request was disallowed.
+}
+
+func (e *DisallowedError) Error() string {
+	d := e.Decision
+	if d.Default {
+		return fmt.Sprintf("robots: %s disallowed for %s: robots.txt unreachable", e.URL, e.Agent)
+	}
+	return fmt.Sprintf("robots: %s disallowed for %s by line %d: disallow: %s", e.URL, e.Agent, d.Line, d.Pattern)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path != "/robots.txt" && req.Context().Value(robotsFetchKey{}) == nil {
+		agent := t.Agent
+		if agent == "" {
+			agent = ProductToken(req.Header.Get("User-Agent"))
+		}
+		rawurl := req.URL.String()
+		res, err := t.Cache.Fetch(req.Context(), rawurl)
+		if err == nil {
+			if d := res.Explain(agent, rawurl); !d.Allowed {
+				err = &DisallowedError{URL: rawurl, Agent: agent, Decision: d}
+			}
+		}
+		if err != nil {
+			// A RoundTripper must close the body, even on
+			// errors.
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
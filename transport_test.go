@@ -0,0 +1,123 @@
+package robots
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "user-agent: *\ndisallow: /private\n\nuser-agent: otherbot\ndisallow: /\n")
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	cache := &Cache{}
+	client := &http.Client{Transport: &Transport{Agent: "Crawlerbot", Cache: cache}}
+	// The cache fetches robots.txt through the same transport.
+	cache.Fetcher = &Fetcher{Client: client}
+
+	resp, err := client.Get(ts.URL + "/public")
+	if err != nil {
+		t.Fatalf("allowed request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = client.Get(ts.URL + "/private/page.html")
+	var de *DisallowedError
+	if !errors.As(err, &de) {
+		t.Fatalf("disallowed request returned %v, want *DisallowedError", err)
+	}
+	if de.Agent != "Crawlerbot" || de.Decision.Pattern != "/private" || de.Decision.Line != 2 {
+		t.Errorf("DisallowedError = %+v", de)
+	}
+	if want := "disallowed for Crawlerbot by line 2: disallow: /private"; !strings.Contains(de.Error(), want) {
+		t.Errorf("de.Error() = %q, want it to contain %q", de.Error(), want)
+	}
+
+	if want := []string{"/robots.txt", "/public"}; fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Errorf("server got requests %v, want %v", requests, want)
+	}
+}
+
+// TestTransportRobotsRedirect checks that a Cache fetching through a
+// Transport follows a redirect of robots.txt to another path, rather
+// than waiting on its own fetch.
+func TestTransportRobotsRedirect(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path == "/robots.txt" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer ts.Close()
+
+	cache := &Cache{Timeout: 5 * time.Second}
+	client := &http.Client{Transport: &Transport{Agent: "Crawlerbot", Cache: cache}}
+	cache.Fetcher = &Fetcher{Client: client}
+
+	resp, err := client.Get(ts.URL + "/private")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if s, _ := cache.State(ts.URL); s.Status != Redirected {
+		t.Errorf("status of robots.txt = %v, want %v", s.Status, Redirected)
+	}
+	if want := []string{"/robots.txt", "/login", "/private"}; fmt.Sprint(requests) != fmt.Sprint(want) {
+		t.Errorf("server got requests %v, want %v", requests, want)
+	}
+}
+
+func TestTransportUserAgent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "user-agent: otherbot\ndisallow: /\n")
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &Transport{Cache: &Cache{}}}
+	for _, test := range []struct {
+		ua   string
+		want bool
+	}{
+		{"Mozilla/5.0 (compatible; OtherBot/1.0)", false},
+		{"Crawlerbot/2.1", true},
+	} {
+		req, _ := http.NewRequest("GET", ts.URL+"/page", nil)
+		req.Header.Set("User-Agent", test.ua)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if got := err == nil; got != test.want {
+			t.Errorf("User-Agent %q: allowed = %t, want %t (err: %v)", test.ua, got, test.want, err)
+		}
+	}
+}
+
+func TestTransportUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &Transport{Agent: "Crawlerbot", Cache: &Cache{}}}
+	_, err := client.Get(ts.URL + "/page")
+	var de *DisallowedError
+	if !errors.As(err, &de) || !de.Decision.Default {
+		t.Fatalf("request returned %v, want default *DisallowedError", err)
+	}
+}
@@ -1,10 +1,9 @@
 package robots
 
 import (
-	"fmt"
 	"net/url"
-	"regexp"
 	"strings"
+	"time"
 )
 
 // member represents a group-member record as defined in Google's
@@ -13,55 +12,112 @@ import (
 type member struct {
 	allow   bool
 	path    string
-	pattern *regexp.Regexp
+	line    int // line of the robots.txt file the record came from
+	pattern pattern
+	// length is the length of path as it is compared with URLs,
+	// which decides precedence among members.
+	length int
 }
 
 // Check whether the given path is matched by this record.
 func (m *member) match(path string) bool {
-	return m.pattern.MatchString(path)
+	return m.pattern.match(path)
 }
 
 // A group-member record specifies a path to which it
 // applies. Internally to this package, we need an efficient way of
 // matching that path, which possibly includes metacharacters * and
-// $. compile() compiles the given path to a regular expression
-// denoting the patterns we will accept.
-func (m *member) compile() {
-	// This approach to handling matches is derived from temoto's:
-	// https://github.com/temoto/robotstxt/blob/master/parser.go
-	pattern := regexp.QuoteMeta(m.path)
-	pattern = "^" + pattern // But with an added start-of-line
-	pattern = strings.Replace(pattern, `\*`, `.*`, -1)
-	pattern = strings.Replace(pattern, `\$`, `$`, -1)
-	// FIXME: What do I do in case of error?
-	r, err := regexp.Compile(pattern)
-	if err != nil {
-		fmt.Printf("oh no! %v\n", err)
+// $. compile() compiles the given path to a pattern; see
+// compilePattern.
+func (m *member) compile(c config) {
+	path := m.path
+	if c.rfc9309 {
+		path = percentEncode(path)
 	}
-	m.pattern = r
+	m.pattern = compilePattern(path)
+	m.length = len(path)
 }
 
 // A group is an ordered list of members. The members are ordered from
 // longest path to shortest path. This allows efficient matching of
 // paths to members: when evaluated sequentially, the first match must
 // be the longest.
+//
+// Once all of its members have been added, a group is indexed, so
+// that the first match can be found without a sequential scan.
+//
+// A group may also carry a crawl delay. It is only meaningful if
+// hasDelay is true.
 type group struct {
-	members []*member
+	members  []*member
+	index    *ruleIndex // nil until the group is indexed
+	delay    time.Duration
+	hasDelay bool
 }
 
-func (g *group) addMember(m *member) {
+func (g *group) addMember(m *member, c config) {
 	// Maintain type invariant: a member must have its pattern
 	// compiled before use.
-	m.compile()
+	m.compile(c)
 	// Maintain type invariant: the members of a group must always
 	// be sorted by length of path, descending.
-	g.members = insertMemberMaintainingOrder(g.members, m)
+	g.members = insertMemberMaintainingOrder(g.members, m, c.rfc9309)
+	g.index = nil
+}
+
+// buildIndex indexes the members of g.
+func (g *group) buildIndex() {
+	g.index = newRuleIndex(g.members)
+}
+
+// find returns the first member of g matching path. Because of the
+// ordering of members, this is the longest match.
+func (g *group) find(path string) (*member, bool) {
+	if g.index != nil {
+		return g.index.find(path)
+	}
+	return g.scan(path)
 }
 
-func insertMemberMaintainingOrder(a []*member, m *member) []*member {
+// scan is find without the index.
+func (g *group) scan(path string) (*member, bool) {
+	for _, member := range g.members {
+		if member.match(path) {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+// setDelay records a crawl delay for g. Crawl-delay is not part of
+// Google's specification, so there is no rule for a group declaring
+// it more than once. We keep the longest delay: a crawler consulting
+// it wants to be polite, and the longest delay is the politest.
+func (g *group) setDelay(d time.Duration) {
+	if g.hasDelay && g.delay >= d {
+		return
+	}
+	g.delay = d
+	g.hasDelay = true
+}
+
+// insertMemberMaintainingOrder inserts m into a, which is sorted by
+// length of path as compared, descending. Among members with paths of
+// the same length, m is placed first, so that the later rule wins. If
+// allowFirst is true, m is instead placed after any allow rules of
+// the same length, so that allow rules win.
+//
+// In RFC 9309 mode, the length is that of the percent-encoded path,
+// so that patterns written differently that match the same octets are
+// equally specific.
+func insertMemberMaintainingOrder(a []*member, m *member, allowFirst bool) []*member {
 	a = append(a, m)
 	for i := len(a) - 1; i > 0; i-- {
-		if len(a[i].path) < len(a[i-1].path) {
+		if a[i].length < a[i-1].length {
+			return a
+		}
+		if allowFirst && a[i].length == a[i-1].length &&
+			a[i-1].allow && !a[i].allow {
 			return a
 		}
 		a[i], a[i-1] = a[i-1], a[i]
@@ -72,35 +128,26 @@ func insertMemberMaintainingOrder(a []*member, m *member) []*member {
 // An agent represents a group of rules that a named robots agent
 // might match. Its compile() method must be called prior to use.
 type agent struct {
-	name    string
-	group   group
-	pattern *regexp.Regexp
+	name  string
+	lower string // name in lowercase
+	token string // product token of name
+	group group
 }
 
-// Test whether the given robots agent string matches this agent.
+// Test whether the given robots agent string matches this agent by
+// prefix. This is only used for legacy prefix matching.
 func (a *agent) match(name string) bool {
-	return a.pattern.MatchString(strings.ToLower(name))
+	return a.lower == "*" || strings.HasPrefix(strings.ToLower(name), a.lower)
 }
 
-// A agent specifies a robots agent to which it applies. Internally to
-// this package, we need an efficient way of matching that name, which
-// includes no metacharacters. However, we will treat the special case
-// "*" as matching all agents for which no other match
-// exists. compile() compiles the amended name to a regular expression
-// denoting the patterns we will accept.
+// A agent specifies a robots agent to which it applies. compile()
+// prepares the forms of its name used for matching: its product
+// token, and for legacy prefix matching, its name in lowercase. We
+// treat the special case "*" as matching all agents for which no
+// other match exists.
 func (a *agent) compile() {
-	pattern := regexp.QuoteMeta(a.name)
-	if pattern == `\*` {
-		pattern = strings.Replace(pattern, `\*`, `.*`, -1)
-	}
-	pattern = "^" + pattern
-	pattern = strings.ToLower(pattern)
-	// FIXME: What do I do in case of error?
-	r, err := regexp.Compile(pattern)
-	if err != nil {
-		fmt.Printf("oh no! %v\n", err)
-	}
-	a.pattern = r
+	a.lower = strings.ToLower(a.name)
+	a.token = productToken(a.name)
 }
 
 // robotsdata represents the result of parsing a robots.txt file. To
@@ -114,14 +161,21 @@ type robotsdata struct {
 	// sequentially, the first matching agent will be the longest
 	// match as well.
 	agents   []*agent
-	sitemaps []string // Absolute URLs of sitemaps in robots.txt.
+	tokens   map[string]*agent // agents by product token
+	groups   []*Group          // Groups as they occur in the source.
+	sitemaps []string          // Absolute URLs of sitemaps in robots.txt.
+	config   config
+	// truncated is true if the file was longer than the size
+	// limit, and its end was not parsed.
+	truncated bool
 }
 
 // Robots represents an object whose methods govern access to URLs
 // within the scope of a robots.txt file, and what sitemaps, if any,
 // have been discovered during parsing.
 type Robots struct {
-	allow bool // default crawl setting
+	allow  bool   // default crawl setting
+	status Status // how the robots.txt file was obtained
 	*robotsdata
 }
 
@@ -141,7 +195,8 @@ func makeRobots(status int, data *robotsdata) *Robots {
 }
 
 func (r *Robots) setAllow(status int) {
-	if status >= 500 && status < 600 {
+	r.status = statusOf(status)
+	if r.status == Unreachable {
 		r.allow = false
 		return
 	}
@@ -149,16 +204,45 @@ func (r *Robots) setAllow(status int) {
 	return
 }
 
+// Status reports how the robots.txt file behind r was obtained.
+func (r *Robots) Status() Status {
+	return r.status
+}
+
+// Truncated reports whether the robots.txt file behind r was longer
+// than the size limit, so that the lines after the limit were ignored.
+// See SizeLimit.
+func (r *Robots) Truncated() bool {
+	return r.truncated
+}
+
 // bestAgent matches an agent string against all of the agents in
 // r. It returns a pointer to the best matching agent, and a boolen
 // indicating whether a match was found.
+//
+// The product token of name is looked up among the product tokens of
+// the agents. If it is not found, the agent for "*" is used, if there
+// is one. With legacy prefix matching, the longest agent that is a
+// prefix of name is used instead.
 func (r *Robots) bestAgent(name string) (*agent, bool) {
-	for _, agent := range r.agents {
-		if agent.match(name) {
+	if r.config.fullUserAgents {
+		name = ProductToken(name)
+	}
+	if r.config.prefixAgents {
+		for _, agent := range r.agents {
+			if agent.match(name) {
+				return agent, true
+			}
+		}
+		return nil, false
+	}
+	if token := productToken(name); token != "" {
+		if agent, ok := r.tokens[token]; ok {
 			return agent, true
 		}
 	}
-	return nil, false
+	agent, ok := r.tokens["*"]
+	return agent, ok
 }
 
 // addAgents adds a slice of agents to that maintained by r.
@@ -166,13 +250,60 @@ func (r *Robots) bestAgent(name string) (*agent, bool) {
 // the parser may generate multiple agent objects from a single
 // group of rules.
 func (r *robotsdata) addAgents(agents []*agent) {
+	if r.tokens == nil {
+		r.tokens = map[string]*agent{}
+	}
 	for _, agent := range agents {
 		// Maintain type invariant: all contained agents
 		// must have patterns compiled before use.
 		agent.compile()
+		if r.mergeAgent(agent) {
+			continue
+		}
 		// Maintain type invariant: r.agents must always be
 		// sorted by length of agent name, descending.
 		r.agents = insertAgentMaintainingOrder(r.agents, agent)
+		if agent.token == "" {
+			// A name without a product token can only be
+			// matched by prefix.
+			continue
+		}
+		r.tokens[agent.token] = agent
+	}
+}
+
+// mergeAgent adds the rules of a to an agent already in r that is
+// matched the same way: one with the same product token, or with
+// legacy prefix matching, the same name compared without regard to
+// case. It reports whether there was such an agent.
+//
+// Both Google's specification and RFC 9309 say that the rules of
+// groups naming the same agent are combined.
+func (r *robotsdata) mergeAgent(a *agent) bool {
+	for _, other := range r.agents {
+		if r.config.prefixAgents || a.token == "" {
+			if other.lower != a.lower {
+				continue
+			}
+		} else if other.token != a.token {
+			continue
+		}
+		for _, m := range a.group.members {
+			other.group.addMember(m, r.config)
+		}
+		if a.group.hasDelay {
+			other.group.setDelay(a.group.delay)
+		}
+		return true
+	}
+	return false
+}
+
+// buildIndex indexes the groups of all agents in r. It is called
+// once r is complete.
+func (r *robotsdata) buildIndex() {
+	for _, agent := range r.agents {
+		agent.group.buildIndex()
 	}
 }
 
@@ -191,8 +322,26 @@ func insertAgentMaintainingOrder(a []*agent, t *agent) []*agent {
 // The specification requires sitemap URLs in robots.txt files to be
 // absolute, but this is the responsibility of the robots.txt author.
 func (r *Robots) Sitemaps() []string {
-	// TODO: Does this need to be immutable?
-	return r.sitemaps
+	return append([]string(nil), r.sitemaps...)
+}
+
+// CrawlDelay takes a string naming a user agent. It returns the crawl
+// delay declared by the group of rules that best matches name, and a
+// boolean indicating whether such a delay was declared.
+//
+// The group is chosen the same way as for Test: if the best matching
+// group declares no delay, no delay is reported, even if some other
+// group (like "*") does declare one.
+//
+// A request-rate line, such as "Request-rate: 1/5s", declares the
+// delay between requests that achieves its rate. If a group declares
+// more than one delay, the longest is reported.
+func (r *Robots) CrawlDelay(name string) (time.Duration, bool) {
+	agent, ok := r.bestAgent(name)
+	if !ok || !agent.group.hasDelay {
+		return 0, false
+	}
+	return agent.group.delay, true
 }
 
 // Test takes an agent string and a rawurl string and checks whether the
@@ -214,26 +363,88 @@ func (r *Robots) Test(name, rawurl string) bool {
 // be provided if this is not the case. To ensure the Robots object is
 // applicable to rawurl, use the Locate function.
 func (r *Robots) Tester(name string) func(rawurl string) bool {
-	agent, ok := r.bestAgent(name)
+	// An agent that isn't matched is nil, and uses the default
+	// allow state.
+	agent, _ := r.bestAgent(name)
+	return func(rawurl string) bool {
+		return r.decide(agent, rawurl).Allowed
+	}
+}
+
+// A Decision explains the result of testing a URL against a Robots
+// object: which group of rules was consulted, and which rule, if any,
+// decided the result.
+type Decision struct {
+	Allowed bool   // Whether the URL may be crawled, as reported by Test.
+	Agent   string // Name of the matched agent, or "" if none matched.
+	Path    string // Path that was tested, or "" if the URL was invalid.
+
+	// Default is true if no rule matched. In that case, Allowed
+	// is the default allow state, which is determined by the
+	// status code of the robots.txt response.
+	Default bool
+
+	// If Default is false, these describe the rule that matched:
+	// whether it was an allow rule, its pattern as written, and
+	// the line of the robots.txt file it came from. The line is 0
+	// for the rule implied by RFC 9309 that /robots.txt is always
+	// allowed.
+	Allow   bool
+	Pattern string
+	Line    int
+}
+
+// Explain takes an agent string and a rawurl string, and reports how
+// r decides whether name may access the path component of
+// rawurl. The Allowed field of the result is the same as the result
+// of Test.
+func (r *Robots) Explain(name, rawurl string) Decision {
+	agent, _ := r.bestAgent(name)
+	return r.decide(agent, rawurl)
+}
+
+// decide tests rawurl against the rules of agent, which may be nil if
+// no agent matched.
+func (r *Robots) decide(agent *agent, rawurl string) Decision {
+	d := Decision{
+		Allowed: r.allow,
+		Default: true,
+	}
+	if agent != nil {
+		d.Agent = agent.name
+	}
+	path, ok := r.path(rawurl)
 	if !ok {
-		// An agent that isn't matched uses default allow state.
-		return func(_ string) bool {
-			return r.allow
-		}
+		return d
 	}
-	return func(rawurl string) bool {
-		path, ok := robotsPath(rawurl)
-		if !ok {
-			return r.allow
-		}
-		for _, member := range agent.group.members {
-			if member.match(path) {
-				return member.allow
-			}
-		}
-		// No applicable rule: return default robots allow state.
-		return r.allow
+	d.Path = path
+	if r.config.rfc9309 && path == "/robots.txt" {
+		d.Allowed = true
+		d.Default = false
+		d.Allow = true
+		d.Pattern = path
+		return d
+	}
+	if agent == nil {
+		return d
 	}
+	if member, ok := agent.group.find(path); ok {
+		d.Allowed = member.allow
+		d.Default = false
+		d.Allow = member.allow
+		d.Pattern = member.path
+		d.Line = member.line
+	}
+	return d
+}
+
+// path returns the part of rawurl that r matches against. Under RFC
+// 9309, this is percent-encoded.
+func (r *Robots) path(rawurl string) (string, bool) {
+	if r.config.rfc9309 {
+		return escapedRobotsPath(rawurl)
+	}
+	return robotsPath(rawurl)
 }
 
 // robotsPath returns the part of a URL that robots.txt will match
@@ -254,3 +465,21 @@ func robotsPath(rawurl string) (string, bool) {
 	}
 	return path, true
 }
+
+// escapedRobotsPath is like robotsPath, but the path is
+// percent-encoded as it appeared in rawurl, normalized by
+// percentEncode.
+func escapedRobotsPath(rawurl string) (string, bool) {
+	parsed, err := url.Parse(rawurl)
+	if err != nil {
+		return "", false
+	}
+	path := parsed.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+	return percentEncode(path), true
+}
@@ -0,0 +1,157 @@
+package robots
+
+import (
+	"strings"
+)
+
+// browserProducts are products that appear in the User-Agent headers
+// of browsers. Crawlers imitating browsers include them, so they
+// never identify a crawler.
+var browserProducts = map[string]bool{
+	"mozilla":     true,
+	"applewebkit": true,
+	"chrome":      true,
+	"chromium":    true,
+	"safari":      true,
+	"gecko":       true,
+	"firefox":     true,
+	"version":     true,
+	"mobile":      true,
+	"edge":        true,
+	"edg":         true,
+	"opera":       true,
+	"presto":      true,
+	"trident":     true,
+	"like":        true, // as in "like Gecko"
+}
+
+// platformProducts are names of platforms that appear in the comments
+// of browsers' User-Agent headers, sometimes with a version.
+var platformProducts = map[string]bool{
+	"compatible": true,
+	"linux":      true,
+	"android":    true,
+	"windows":    true,
+	"macintosh":  true,
+	"iphone":     true,
+	"ipad":       true,
+	"ipod":       true,
+	"cros":       true,
+	"ubuntu":     true,
+	"fedora":     true,
+}
+
+// isCrawlerProduct reports whether the product name might identify a
+// crawler: whether it is neither part of a browser's header nor a
+// platform.
+func isCrawlerProduct(name string) bool {
+	name = strings.ToLower(name)
+	return !browserProducts[name] && !platformProducts[name]
+}
+
+// ProductToken extracts the product token identifying a crawler from
+// the value of a User-Agent header. For example, given
+//
+//	Mozilla/5.0 (compatible; Crawlerbot/2.1; +https://example.com/bot)
+//
+// it returns "Crawlerbot". The result can be used as the agent name
+// given to Test, Tester, Explain and CrawlDelay.
+//
+// A crawler wrapped in a Mozilla-compatible header is found in the
+// comment that begins with "compatible". Otherwise, the first product
+// that is not part of a browser's header is used, without its
+// version. Failing that, the first product with a version inside any
+// comment that is not part of a browser's header or a platform is
+// used, as some crawlers, like Applebot, name themselves there
+// without "compatible". If every product is part of a browser's
+// header, the first is used. If uaHeader has no products, ProductToken
+// returns "".
+func ProductToken(uaHeader string) string {
+	products, comments := splitUserAgent(uaHeader)
+	for _, comment := range comments {
+		entries := strings.Split(comment, ";")
+		for i, entry := range entries {
+			if !strings.EqualFold(strings.TrimSpace(entry), "compatible") {
+				continue
+			}
+			for _, entry := range entries[i+1:] {
+				if name, ok := productName(entry); ok && isCrawlerProduct(name) {
+					return name
+				}
+			}
+		}
+	}
+	first := ""
+	for _, product := range products {
+		name, ok := productName(product)
+		if !ok {
+			continue
+		}
+		if !browserProducts[strings.ToLower(name)] {
+			return name
+		}
+		if first == "" {
+			first = name
+		}
+	}
+	for _, comment := range comments {
+		for _, entry := range strings.Split(comment, ";") {
+			// Without a version, a single word in a comment is
+			// more likely to be a platform than a crawler.
+			if !strings.Contains(entry, "/") {
+				continue
+			}
+			if name, ok := productName(entry); ok && isCrawlerProduct(name) {
+				return name
+			}
+		}
+	}
+	return first
+}
+
+// splitUserAgent splits a User-Agent header into the products outside
+// parentheses and the comments inside them. Nested parentheses are
+// part of the enclosing comment.
+func splitUserAgent(ua string) (products, comments []string) {
+	depth := 0
+	start := 0
+	for i, c := range ua {
+		switch {
+		case c == '(':
+			if depth == 0 {
+				products = append(products, strings.Fields(ua[start:i])...)
+				start = i + 1
+			}
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				comments = append(comments, ua[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth > 0 {
+		comments = append(comments, ua[start:])
+	} else {
+		products = append(products, strings.Fields(ua[start:])...)
+	}
+	return products, comments
+}
+
+// productName returns the name of a product of the form "name" or
+// "name/version", and whether s has that form.
+func productName(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	name := s
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		name = s[:i]
+		if strings.ContainsAny(s[i+1:], " \t") {
+			return "", false
+		}
+	}
+	if !isAgentName(name) || name == "*" {
+		return "", false
+	}
+	return name, true
+}
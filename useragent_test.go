@@ -0,0 +1,63 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProductToken(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"Crawlerbot", "Crawlerbot"},
+		{"Crawlerbot/2.1", "Crawlerbot"},
+		{"  Crawlerbot/2.1  ", "Crawlerbot"},
+		{"Mozilla/5.0 (compatible; Crawlerbot/2.1; +https://ex.com/bot)", "Crawlerbot"},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", "bingbot"},
+		{"Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; Googlebot/2.1; " +
+			"+http://www.google.com/bot.html) Chrome/120.0.6099.129 Safari/537.36", "Googlebot"},
+		{"Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 " +
+			"(KHTML, like Gecko) Chrome/120.0.6099.129 Mobile Safari/537.36 " +
+			"(compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "Googlebot"},
+		{"Googlebot-Image/1.0", "Googlebot-Image"},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", "facebookexternalhit"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 14_7_1 like Mac OS X) AppleWebKit/605.1.15 " +
+			"(KHTML, like Gecko) Version/14.1.2 Mobile/15E148 Safari/604.1 " +
+			"(Applebot/0.1; +http://www.apple.com/go/applebot)", "Applebot"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 " +
+			"(KHTML, like Gecko) Version/17.0 Safari/605.1.15", "Mozilla"},
+		{"Mozilla/5.0 (Windows NT 6.1; Trident/7.0; rv:11.0) like Gecko", "Mozilla"},
+		{"Mozilla/5.0 (Linux; Android/9; Trident/7.0)", "Mozilla"},
+		{"Mozilla/5.0 (X11; Linux x86_64; Crawlerbot/3.0) Firefox/120.0", "Crawlerbot"},
+		{"Mozilla/5.0 (X11; Linux x86_64) Chrome/120.0 Safari/537.36 DuckDuckBot/1.1", "DuckDuckBot"},
+		{"Mozilla/5.0 (compatible; MSIE 9.0; Windows NT 6.1; Trident/5.0)", "Mozilla"},
+		{"Mozilla/5.0 (compatible; Crawlerbot/1.0", "Crawlerbot"},
+		{"(just a comment)", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := ProductToken(test.input); got != test.want {
+			t.Errorf("ProductToken(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestFullUserAgents(t *testing.T) {
+	input := "user-agent: crawlerbot\ndisallow: /private\n\nuser-agent: *\ndisallow: /"
+	ua := "Mozilla/5.0 (compatible; Crawlerbot/2.1; +https://ex.com/bot)"
+
+	r, _ := From(200, strings.NewReader(input))
+	if r.Test(ua, "/public") {
+		t.Errorf("without FullUserAgents, %q should use the * group", ua)
+	}
+
+	r, _ = From(200, strings.NewReader(input), FullUserAgents())
+	if !r.Test(ua, "/public") || r.Test(ua, "/private") {
+		t.Errorf("with FullUserAgents, %q should use the crawlerbot group", ua)
+	}
+	if got := r.Explain(ua, "/private").Agent; got != "crawlerbot" {
+		t.Errorf("r.Explain(%q, \"/private\").Agent = %q", ua, got)
+	}
+}
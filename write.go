@@ -0,0 +1,116 @@
+package robots
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// fieldNames are the spellings of fields used when writing robots.txt
+// files.
+var fieldNames = map[membertype]string{
+	itemUserAgent:   "User-agent",
+	itemDisallow:    "Disallow",
+	itemAllow:       "Allow",
+	itemSitemap:     "Sitemap",
+	itemCrawlDelay:  "Crawl-delay",
+	itemRequestRate: "Request-rate",
+}
+
+// WriteTo writes r to w as a robots.txt file in canonical form. Each
+// group is written in the order it appeared in the source: first its
+// user-agent lines, then its crawl delay and request rate, then its
+// rules in their original order. Groups are separated by blank lines, and sitemaps
+// follow the last group. Comments, invalid lines, and rules that
+// apply to no agent are not written.
+//
+// Parsing the output with the options r was parsed with yields a
+// Robots object that behaves the same as r, except that the default
+// allow state is not written: it depends on the status code of the
+// response that delivered the file, not its content. The options are
+// not written either.
+func (r *Robots) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	writeRobots(&b, r.groups, r.sitemaps)
+	return b.WriteTo(w)
+}
+
+// MarshalText returns r as a robots.txt file in canonical form. See
+// WriteTo for details.
+func (r *Robots) MarshalText() ([]byte, error) {
+	var b bytes.Buffer
+	writeRobots(&b, r.groups, r.sitemaps)
+	return b.Bytes(), nil
+}
+
+// UnmarshalText parses text as a robots.txt file served with a 200
+// status code, and sets r to the result.
+//
+// The text is interpreted with the options r was made with, so to
+// decode text with options, unmarshal it into a Robots object made by
+// From with those options, like From(200, nil, RFC9309()). A zero
+// Robots object has the default options. There is no size limit: the
+// text is already in memory.
+func (r *Robots) UnmarshalText(text []byte) error {
+	var c config
+	if r.robotsdata != nil {
+		c = r.config
+	}
+	*r = *makeRobots(200, parse(string(text), c))
+	return nil
+}
+
+func writeRobots(b *bytes.Buffer, groups []*Group, sitemaps []string) {
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, name := range g.Agents {
+			writeLine(b, itemUserAgent, name)
+		}
+		if g.HasCrawlDelay {
+			writeLine(b, itemCrawlDelay, formatDelay(g.CrawlDelay))
+		}
+		if g.RequestRate != "" {
+			writeLine(b, itemRequestRate, g.RequestRate)
+		}
+		for _, rule := range g.Rules {
+			typ := itemDisallow
+			if rule.Allow {
+				typ = itemAllow
+			}
+			writeLine(b, typ, rule.Pattern)
+		}
+		if len(g.Rules) == 0 && !g.HasCrawlDelay && g.RequestRate == "" {
+			// An empty disallow rule allows everything, but
+			// it ends the list of agents. Without it, the
+			// agents of this group would join the next.
+			writeLine(b, itemDisallow, "")
+		}
+	}
+	if len(groups) > 0 && len(sitemaps) > 0 {
+		b.WriteString("\n")
+	}
+	for _, sitemap := range sitemaps {
+		writeLine(b, itemSitemap, sitemap)
+	}
+}
+
+func writeLine(b *bytes.Buffer, typ membertype, value string) {
+	b.WriteString(fieldNames[typ])
+	b.WriteString(":")
+	if value != "" {
+		b.WriteString(" ")
+		b.WriteString(value)
+	}
+	b.WriteString("\n")
+}
+
+// formatDelay writes d as a decimal number of seconds, exact to the
+// nanosecond.
+func formatDelay(d time.Duration) string {
+	s := fmt.Sprintf("%d.%09d", d/time.Second, d%time.Second)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
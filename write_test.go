@@ -0,0 +1,163 @@
+package robots
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// withoutLines returns groups with line numbers removed, since they
+// are not preserved by writing.
+func withoutLines(groups []Group) []Group {
+	for i := range groups {
+		for j := range groups[i].Rules {
+			groups[i].Rules[j].Line = 0
+		}
+	}
+	return groups
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	fnames, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agents := []string{"a", "b", "e", "googlebot", "Googlebot-News", "bingbot", "crawler"}
+	paths := []string{"/", "/c", "/d", "/g", "/page", "/folder/page", "/x.htm",
+		"/exact-match", "/images", "/images/my-cool-image.png", "/app.js"}
+
+	for _, fname := range fnames {
+		data, err := os.Open(fname)
+		if err != nil {
+			t.Errorf("couldn't open test data %s", fname)
+			continue
+		}
+		r, _ := From(200, data)
+		data.Close()
+
+		var b bytes.Buffer
+		if _, err := r.WriteTo(&b); err != nil {
+			t.Errorf("%s: r.WriteTo returned error: %v", fname, err)
+		}
+		again, _ := From(200, &b)
+
+		if got, want := withoutLines(again.Groups()), withoutLines(r.Groups()); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: groups after round trip = %+v, want %+v", fname, got, want)
+		}
+		if got, want := again.Sitemaps(), r.Sitemaps(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: sitemaps after round trip = %v, want %v", fname, got, want)
+		}
+		for _, agent := range agents {
+			for _, path := range paths {
+				if got, want := again.Test(agent, path), r.Test(agent, path); got != want {
+					t.Errorf("%s: Test(%q, %q) after round trip = %t, want %t",
+						fname, agent, path, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+	data, err := os.Open("testdata/pathological.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/pathological.canonical.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := From(200, data)
+	got, err := r.MarshalText()
+	if err != nil {
+		t.Errorf("r.MarshalText returned error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("r.MarshalText() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteEmptyGroup(t *testing.T) {
+	// The empty disallow rule separates the groups of a and b.
+	r, _ := From(200, bytes.NewBufferString("user-agent: a\ndisallow:\nuser-agent: b\ndisallow: /"))
+	text, _ := r.MarshalText()
+	var again Robots
+	if err := again.UnmarshalText(text); err != nil {
+		t.Errorf("UnmarshalText returned error: %v", err)
+	}
+	if !again.Test("a", "/") || again.Test("b", "/") {
+		t.Errorf("empty group was not preserved by writing: %q", text)
+	}
+}
+
+// TestWriteRequestRate checks that request-rate lines are written as
+// they were, not as the crawl delays they imply.
+func TestWriteRequestRate(t *testing.T) {
+	input := "user-agent: a\nrequest-rate: 1/5\n\nuser-agent: b\ncrawl-delay: 2\nrequest-rate: 1/1\nrequest-rate: 10/1m 0600-0845\n"
+	want := "User-agent: a\nRequest-rate: 1/5\n\nUser-agent: b\nCrawl-delay: 2\nRequest-rate: 10/1m 0600-0845\n"
+	r, _ := From(200, bytes.NewBufferString(input))
+	text, _ := r.MarshalText()
+	if string(text) != want {
+		t.Errorf("r.MarshalText() = %q, want %q", text, want)
+	}
+
+	data, _ := r.MarshalBinary()
+	var fromText, fromBinary Robots
+	fromText.UnmarshalText(text)
+	fromBinary.UnmarshalBinary(data)
+	for _, again := range []*Robots{&fromText, &fromBinary} {
+		for _, agent := range []string{"a", "b"} {
+			got, _ := again.CrawlDelay(agent)
+			if want, _ := r.CrawlDelay(agent); got != want {
+				t.Errorf("CrawlDelay(%q) after round trip = %v, want %v", agent, got, want)
+			}
+		}
+	}
+}
+
+func TestUnmarshalTextOptions(t *testing.T) {
+	text := []byte("user-agent: googlebot\ndisallow: /\n")
+
+	var plain Robots
+	if err := plain.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText returned error: %v", err)
+	}
+	if !plain.Test("googlebot-news", "/") {
+		t.Errorf("zero Robots did not use default agent matching")
+	}
+
+	prefix, _ := From(200, nil, PrefixAgentMatching())
+	if err := prefix.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText returned error: %v", err)
+	}
+	if prefix.Test("googlebot-news", "/") {
+		t.Errorf("UnmarshalText did not keep PrefixAgentMatching")
+	}
+}
+
+func TestFormatDelay(t *testing.T) {
+	var tests = []struct {
+		input time.Duration
+		want  string
+	}{
+		{0, "0"},
+		{10 * time.Second, "10"},
+		{2500 * time.Millisecond, "2.5"},
+		{time.Second + time.Nanosecond, "1.000000001"},
+	}
+
+	for _, test := range tests {
+		got := formatDelay(test.input)
+		if got != test.want {
+			t.Errorf("formatDelay(%v) = %q, want %q", test.input, got, test.want)
+		}
+		if d, ok := parseDelay(got); !ok || d != test.input {
+			t.Errorf("parseDelay(%q) = %v, want %v", got, d, test.input)
+		}
+	}
+}